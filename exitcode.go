@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/cimile/ai-video-note-generator/videonote"
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+// 退出码，供脚本和CI区分失败原因，修改时需同步更新 README
+const (
+	exitOK         = 0
+	exitError      = 1   // 其他错误，如ffmpeg处理失败、重试后仍失败的接口请求
+	exitUsage      = 2   // 命令行参数错误
+	exitConfig     = 3   // 配置文件或环境变量无效、缺少API密钥
+	exitDependency = 4   // 缺少 ffmpeg、yt-dlp、whisper.cpp 等外部程序
+	exitAuth       = 5   // 接口拒绝了API密钥 (401/403)
+	exitPartial    = 6   // 批量处理中部分输入失败，或笔记中部分内容生成失败
+	exitNoSpeech   = 7   // 转录结果为空，没有检测到语音
+	exitMedia      = 8   // 输入文件损坏、不是音视频或没有音频流
+	exitTimeout    = 124 // 超过 -timeout，与 timeout(1) 一致
+	exitCanceled   = 130 // 被 Ctrl-C 或 SIGTERM 中断
+)
+
+// exitCode 返回 err 对应的退出码
+func exitCode(err error) int {
+	var noExec ffcli.NoExecError
+	if errors.Is(err, flag.ErrHelp) || errors.As(err, &noExec) {
+		return exitUsage
+	}
+	switch videonote.ErrorKind(err) {
+	case videonote.ErrConfig:
+		return exitConfig
+	case videonote.ErrDependency:
+		return exitDependency
+	case videonote.ErrAuth:
+		return exitAuth
+	case videonote.ErrPartial:
+		return exitPartial
+	case videonote.ErrNoSpeech:
+		return exitNoSpeech
+	case videonote.ErrMedia:
+		return exitMedia
+	}
+	if err != nil {
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cimile/ai-video-note-generator/videonote"
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+func infoCommand(config *videonote.Config) *ffcli.Command {
+	return &ffcli.Command{
+		Name:       "info",
+		ShortUsage: "video-note info <file> ...",
+		ShortHelp:  "查看媒体文件的时长、音频流和预计的文本块数",
+		FlagSet:    flag.NewFlagSet("video-note info", flag.ExitOnError),
+		Exec: func(ctx context.Context, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("必须指定要查看的文件")
+			}
+			if err := videonote.CheckFFmpeg(config); err != nil {
+				return err
+			}
+
+			for i, path := range args {
+				if i > 0 {
+					fmt.Println()
+				}
+				info, err := videonote.ProbeMedia(ctx, config, path)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				info.Print(os.Stdout, config)
+				if len(info.AudioStreams()) == 0 {
+					return fmt.Errorf("%s 中没有音频流，无法转录", path)
+				}
+			}
+			return nil
+		},
+	}
+}
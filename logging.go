@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// 日志由 videonote.SetupLogging 配置为全局的 slog，命令行只需按级别格式化输出
+
+func infof(format string, args ...any) { slog.Info(fmt.Sprintf(format, args...)) }
+
+func warnf(format string, args ...any) { slog.Warn(fmt.Sprintf(format, args...)) }
+
+func errorf(format string, args ...any) { slog.Error(fmt.Sprintf(format, args...)) }
+
+// exitf 记录错误并以 code 退出，退出码见 exitcode.go
+func exitf(code int, format string, args ...any) {
+	errorf(format, args...)
+	os.Exit(code)
+}
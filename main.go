@@ -2,286 +2,563 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
+	"github.com/cimile/ai-video-note-generator/videonote"
 	"github.com/peterbourgon/ff/v3/ffcli"
-	"github.com/sashabaranov/go-openai"
 )
 
-type Config struct {
-	OpenAIAPIKey string `json:"openai_api_key"`
-	Model        string `json:"model"`
-}
-
 func main() {
-	config := &Config{}
-	configFile := flag.String("config", "config.json", "配置文件路径")
-	flag.Parse()
-
-	if err := loadConfig(*configFile, config); err != nil {
-		log.Fatalf("加载配置文件失败: %v", err)
+	config := &videonote.Config{}
+	rootFlags := flag.NewFlagSet("video-note", flag.ExitOnError)
+	configFile := rootFlags.String("config", "", "配置文件路径 (默认依次查找 "+strings.Join(videonote.ConfigSearchPaths(), "、")+")")
+	level := rootFlags.String("log-level", "info", "日志级别 (debug, info, warn, error)")
+	verbose := rootFlags.Bool("verbose", false, "输出详细日志 (等同于 -log-level debug)，ffmpeg 等外部程序出错时附上完整输出")
+	json := rootFlags.Bool("json-logs", false, "以JSON格式输出日志，便于程序解析")
+	noColor := rootFlags.Bool("no-color", false, "不使用颜色输出 (输出不是终端或设置了 NO_COLOR 环境变量时自动关闭)")
+	reportPath := rootFlags.String("report", "", "运行结束后将文件数、接口调用次数、token用量和耗时等统计以JSON写入该文件")
+	timeout := rootFlags.Duration("timeout", 0, "整个运行的超时时间，如 30m，超时后终止请求和ffmpeg并清理临时文件 (默认不限制)")
+	webhook := rootFlags.String("webhook", "", "运行结束 (成功或失败) 时将状态、笔记路径和统计以JSON POST到该地址，失败时重试")
+
+	// 先解析全局参数并加载配置，子命令的参数在此之后解析，才能覆盖配置文件中的值
+	rootFlags.Parse(os.Args[1:])
+	if *verbose {
+		*level = "debug"
+	}
+	if err := videonote.SetupLogging(*level, *json, *noColor); err != nil {
+		exitf(exitUsage, "%v", err)
+	}
+	if *webhook != "" {
+		if err := videonote.CheckWebhookURL(*webhook); err != nil {
+			exitf(exitUsage, "%v", err)
+		}
 	}
 
-	if config.OpenAIAPIKey == "" {
-		log.Fatal("OpenAI API Key 不能为空")
+	// version 不需要配置文件，info 只调用本地的 ffprobe，不需要API密钥
+	if command := rootFlags.Arg(0); command != "version" {
+		if err := videonote.PrepareConfig(config, *configFile, command != "info"); err != nil {
+			exitf(exitCode(err), "%v", err)
+		}
 	}
 
 	root := &ffcli.Command{
 		Name:       "video-note",
 		ShortUsage: "video-note [flags] <subcommand>",
+		FlagSet:    rootFlags,
 		Subcommands: []*ffcli.Command{
 			generateCommand(config),
 			transcribeCommand(config),
 			summarizeCommand(config),
+			infoCommand(config),
+			serveCommand(config),
+			versionCommand(),
 		},
 	}
 
-	if err := root.ParseAndRun(context.Background(), os.Args[1:]); err != nil {
-		log.Fatal(err)
+	// 收到 Ctrl-C 或 SIGTERM 时取消 context，让进行中的请求和子进程尽快退出，
+	// 并由各处的 defer 清理临时文件；再次按下 Ctrl-C 则立即退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// -timeout 限制整个运行的时长，超时后取消 context 的效果与 Ctrl-C 相同
+	runCtx := ctx
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
 	}
-}
 
-func loadConfig(path string, config *Config) error {
-	file, err := os.Open(path)
+	err := root.ParseAndRun(runCtx, os.Args[1:])
+	if *reportPath != "" {
+		if err := videonote.WriteReport(*reportPath, videonote.RunReport()); err != nil {
+			errorf("%v", err)
+		}
+	}
+	code := exitCode(err)
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		code = exitCanceled
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		code, err = exitTimeout, fmt.Errorf("操作超时: 运行时间超过 -timeout %s", *timeout)
+	}
+	if *webhook != "" {
+		notifyWebhook(config, *webhook, rootFlags.Arg(0), code, err)
+	}
+	if code == exitCanceled {
+		infof("操作已取消")
+		os.Exit(exitCanceled)
+	}
 	if err != nil {
-		return fmt.Errorf("打开配置文件失败: %w", err)
+		exitf(code, "%v", err)
 	}
-	defer file.Close()
+}
 
-	bytes, err := io.ReadAll(file)
+// notifyWebhook 将运行结果通知 -webhook；运行已被取消或超时，因此使用新的 context，
+// 再次按下 Ctrl-C 可以放弃通知立即退出
+func notifyWebhook(config *videonote.Config, url, command string, code int, err error) {
+	payload := videonote.WebhookPayload{
+		Status:     videonote.WebhookSucceeded,
+		Command:    command,
+		Notes:      videonote.WrittenNotes(),
+		ExitCode:   code,
+		Stats:      videonote.RunReport(),
+		FinishedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	switch code {
+	case exitOK:
+	case exitPartial:
+		payload.Status = videonote.WebhookPartial
+	case exitCanceled:
+		payload.Status = videonote.WebhookCanceled
+	default:
+		payload.Status = videonote.WebhookFailed
+	}
 	if err != nil {
-		return fmt.Errorf("读取配置文件失败: %w", err)
+		payload.Error = err.Error()
 	}
 
-	if err := json.Unmarshal(bytes, config); err != nil {
-		return fmt.Errorf("解析配置文件失败: %w", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := videonote.NotifyWebhook(ctx, config, url, payload); err != nil {
+		errorf("%v", err)
 	}
-
-	return nil
 }
 
-func generateCommand(config *Config) *ffcli.Command {
+func generateCommand(config *videonote.Config) *ffcli.Command {
 	var (
 		videoPath    string
 		outputPath   string
 		summaryRatio float64
+		segmentTime  time.Duration
+		formatName   string
+		timestamps   bool
+		cookiesPath  string
+		jobs         int
+		concurrency  int
+		lang         string
+		translate    bool
+		promptFile   string
+		quiet        bool
+		dryRun       bool
+		keepFiles    bool
+		workDir      string
+		modeName     string
+		diarize      bool
+		stream       bool
+		stats        bool
+		extract      videonote.ExtractOptions
+		cacheDir     string
+		noCache      bool
+		backend      string
+		stateFile    string
+		since        string
+		force        bool
+		sourceLang   string
+		hint         string
+		overwrite    bool
+		noClobber    bool
+		withText     bool
+		overlap      int
+		outputDir    string
+		clean        bool
+		dedup        bool
+		bom          bool
+		refine       int
+		tolerance    float64
+		words        int
+		extractNames string
+		metadata     bool
+		failFast     bool
+		minLength    int
+		tocName      string
+		whisperFmt   string
+		whisperTemp  float64
+		confidence   float64
+		outputNames  string
+		outputTmpl   string
+		glossaryFile string
+		presetName   string
+		pipeline     bool
 	)
 
+	// 预设需要在 Exec 中查看哪些参数被明确指定
+	fs := flag.NewFlagSet("video-note generate", flag.ExitOnError)
 	cmd := &ffcli.Command{
 		Name:       "generate",
-		ShortUsage: "video-note generate [flags] -i video.mp4 -o notes.txt",
+		ShortUsage: "video-note generate [flags] -i video.mp4 -o notes.txt | video-note generate [flags] a.mp4 b.mp4 ...",
 		ShortHelp:  "从视频生成笔记",
-		FlagSet:    flag.NewFlagSet("video-note generate", flag.ExitOnError),
+		FlagSet:    fs,
 		Exec: func(ctx context.Context, args []string) error {
-			if videoPath == "" {
-				return fmt.Errorf("必须指定视频文件 (-i)")
+			// 预设只填充没有明确指定的参数，必须在读取参数之前应用
+			if err := videonote.ApplyPreset(fs, config, presetName); err != nil {
+				return err
 			}
-
-			if outputPath == "" {
-				ext := filepath.Ext(videoPath)
-				outputPath = strings.TrimSuffix(videoPath, ext) + ".txt"
+			// -i 与命令行末尾的文件一起处理
+			sources := args
+			if videoPath != "" {
+				sources = append([]string{videoPath}, args...)
+			}
+			if len(sources) == 0 {
+				return fmt.Errorf("必须指定视频文件 (-i 或直接列出文件)")
 			}
 
-			// 临时文件
-			tmpDir, err := os.MkdirTemp("", "video-note-")
+			format, err := videonote.ParseFormat(formatName)
 			if err != nil {
-				return fmt.Errorf("创建临时目录失败: %w", err)
+				return err
 			}
-			defer os.RemoveAll(tmpDir)
-
-			audioPath := filepath.Join(tmpDir, "audio.mp3")
-			transcriptPath := filepath.Join(tmpDir, "transcript.txt")
 
-			// 1. 提取音频
-			log.Printf("正在从视频中提取音频...")
-			if err := extractAudio(videoPath, audioPath); err != nil {
-				return fmt.Errorf("提取音频失败: %w", err)
+			prompt, err := videonote.LoadPromptTemplate(promptFile)
+			if err != nil {
+				return err
 			}
 
-			// 2. 音频转文字
-			log.Printf("正在将音频转换为文字...")
-			if err := transcribeAudio(ctx, config.OpenAIAPIKey, config.Model, audioPath, transcriptPath); err != nil {
-				return fmt.Errorf("音频转文字失败: %w", err)
+			mode, err := videonote.ParseMode(modeName)
+			if err != nil {
+				return err
 			}
-
-			// 3. 生成摘要
-			log.Printf("正在生成笔记摘要...")
-			if err := summarizeText(ctx, config.OpenAIAPIKey, config.Model, transcriptPath, outputPath, summaryRatio); err != nil {
-				return fmt.Errorf("生成摘要失败: %w", err)
+			if err := videonote.CheckModeFormat(mode, format); err != nil {
+				return err
 			}
-
-			log.Printf("笔记已生成: %s", outputPath)
-			return nil
-		},
-	}
-
-	cmd.FlagSet.StringVar(&videoPath, "i", "", "输入视频文件路径")
-	cmd.FlagSet.StringVar(&outputPath, "o", "", "输出笔记文件路径 (默认与视频同名)")
-	cmd.FlagSet.Float64Var(&summaryRatio, "ratio", 0.2, "摘要比例 (0.1-0.5)")
-
-	return cmd
-}
-
-func extractAudio(videoPath, audioPath string) error {
-	cmd := exec.Command("ffmpeg", "-i", videoPath, "-vn", "-acodec", "libmp3lame", audioPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ffmpeg执行失败: %w\n输出: %s", err, string(output))
-	}
-	return nil
-}
-
-func transcribeAudio(ctx context.Context, apiKey, model, audioPath, outputPath string) error {
-	client := openai.NewClient(apiKey)
-
-	file, err := os.Open(audioPath)
-	if err != nil {
-		return fmt.Errorf("打开音频文件失败: %w", err)
-	}
-	defer file.Close()
-
-	req := openai.AudioTranscriptionRequest{
-		Model:    model,
-		FilePath: audioPath,
-	}
-
-	transcript, err := client.CreateTranscription(ctx, req)
-	if err != nil {
-		return fmt.Errorf("调用OpenAI API失败: %w", err)
-	}
-
-	outputFile, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("创建输出文件失败: %w", err)
-	}
-	defer outputFile.Close()
-
-	if _, err := outputFile.WriteString(transcript.Text); err != nil {
-		return fmt.Errorf("写入转录文本失败: %w", err)
-	}
-
-	return nil
-}
-
-func summarizeText(ctx context.Context, apiKey, model, inputPath, outputPath string, ratio float64) error {
-	// 限制摘要比例范围
-	if ratio < 0.1 {
-		ratio = 0.1
-	} else if ratio > 0.5 {
-		ratio = 0.5
-	}
-
-	client := openai.NewClient(apiKey)
-
-	// 读取转录文本
-	transcript, err := os.ReadFile(inputPath)
-	if err != nil {
-		return fmt.Errorf("读取转录文件失败: %w", err)
-	}
-
-	// 分割文本为多个块，避免超出token限制
-	chunks := splitTextIntoChunks(string(transcript), 3000)
-	var summaries []string
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(chunks))
-
-	for i, chunk := range chunks {
-		wg.Add(1)
-		go func(idx int, text string) {
-			defer wg.Done()
-
-			// 等待一段时间，避免API请求过于频繁
-			time.Sleep(time.Duration(idx*2) * time.Second)
-
-			prompt := fmt.Sprintf(`请为以下视频转录内容生成详细的笔记摘要，保留关键信息和重要细节:
-			
-内容:
-%s
-
-请生成一份简洁但信息丰富的摘要，约占原文长度的%.0f%%。`, text, ratio*100)
-
-			resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-				Model: model,
-				Messages: []openai.ChatCompletionMessage{
-					{
-						Role:    openai.ChatMessageRoleUser,
-						Content: prompt,
-					},
-				},
-				Temperature: 0.3,
-				MaxTokens:   int(float64(len(text)) * ratio * 1.5),
-			})
-
+			if dedup && mode == videonote.ModeMapReduce {
+				return fmt.Errorf("-dedup 不能与 -mode map-reduce 同时使用，整合摘要时已会合并重复的要点")
+			}
+			if bom {
+				if err := videonote.CheckBOM(format); err != nil {
+					return err
+				}
+			}
+			if refine < 0 || tolerance <= 0 {
+				return fmt.Errorf("-refine 不能为负数，-ratio-tolerance 必须大于 0")
+			}
+			if minLength < 0 {
+				return fmt.Errorf("-min-length 不能为负数")
+			}
+			if err := videonote.CheckRatio(summaryRatio); err != nil {
+				return err
+			}
+			if err := videonote.CheckWords(words, format); err != nil {
+				return err
+			}
+			// 边转录边摘要时还不知道完整的转录，无法按总字数分配，也无法在摘要之前标注说话人
+			if pipeline && (words > 0 || diarize) {
+				return fmt.Errorf("-pipeline 不能与 -words 或 -diarize 同时使用")
+			}
+			extractors, err := videonote.ParseExtractors(extractNames)
 			if err != nil {
-				errChan <- fmt.Errorf("生成第%d部分摘要失败: %w", idx+1, err)
-				return
+				return err
+			}
+			if err := videonote.CheckExtract(extractors, format); err != nil {
+				return err
+			}
+			toc, err := videonote.ParseTOC(tocName)
+			if err != nil {
+				return err
+			}
+			if err := videonote.CheckTOC(toc, format); err != nil {
+				return err
+			}
+			artifacts, err := videonote.ParseOutputs(outputNames)
+			if err != nil {
+				return err
+			}
+			if err := videonote.CheckOutputTemplate(outputTmpl); err != nil {
+				return err
+			}
+			glossary, err := videonote.LoadGlossary(glossaryFile)
+			if err != nil {
+				return err
 			}
 
-			summaries = append(summaries, resp.Choices[0].Message.Content)
-		}(i, chunk)
-	}
+			opts := videonote.GenerateOptions{
+				Ratio:             summaryRatio,
+				Format:            format,
+				Mode:              mode,
+				Prompt:            prompt,
+				Concurrency:       concurrency,
+				Language:          lang,
+				SegmentTime:       segmentTime,
+				Timestamps:        timestamps,
+				Translate:         translate,
+				CookiesPath:       cookiesPath,
+				Quiet:             quiet,
+				DryRun:            dryRun,
+				DryRunOutput:      os.Stdout,
+				KeepFiles:         keepFiles,
+				WorkDir:           workDir,
+				Diarize:           diarize,
+				Stream:            stream,
+				Stats:             stats,
+				Extract:           extract,
+				IncludeTranscript: withText,
+				ChunkOverlap:      overlap,
+				CleanTranscript:   clean,
+				Dedup:             dedup,
+				BOM:               bom,
+				Refine:            refine,
+				RatioTolerance:    tolerance,
+				Words:             words,
+				Extractors:        extractors,
+				Metadata:          metadata,
+				FailFast:          failFast,
+				MinLength:         minLength,
+				TOC:               toc,
+				Outputs:           artifacts,
+				OutputTemplate:    outputTmpl,
+				Glossary:          glossary,
+				Preset:            presetName,
+				Pipeline:          pipeline,
+			}
+			if !noCache {
+				opts.CacheDir = cacheDir
+			}
+			if err := videonote.ValidateSourceLanguage(sourceLang); err != nil {
+				return err
+			}
+			opts.SourceLanguage = sourceLang
+			opts.TranscribePrompt = hint
+			opts.Transcriber = backend
+			if err := videonote.ValidateWhisperOptions(whisperFmt, whisperTemp); err != nil {
+				return err
+			}
+			opts.WhisperFormat, opts.WhisperTemperature = whisperFmt, float32(whisperTemp)
+			if confidence > 0 {
+				return fmt.Errorf("-confidence-threshold 不能为正数 (avg_logprob 不大于 0)")
+			}
+			opts.ConfidenceThreshold = confidence
+			if force {
+				if noClobber {
+					return fmt.Errorf("-force 与 -no-clobber 不能同时使用")
+				}
+				overwrite = true
+			}
+			if opts.Overwrite, err = videonote.OverwritePolicy(overwrite, noClobber); err != nil {
+				return err
+			}
 
-	go func() {
-		wg.Wait()
-		close(errChan)
-	}()
+			if err := config.ValidateChunkOverlap(overlap); err != nil {
+				return err
+			}
+			if err := extract.Validate(); err != nil {
+				return err
+			}
 
-	for err := range errChan {
-		if err != nil {
-			return err
-		}
-	}
+			if translate && lang != "" && lang != "en" {
+				return fmt.Errorf("-translate 只能将音频翻译为英文，不能与 -lang %s 同时使用", lang)
+			}
 
-	// 合并所有摘要部分
-	combinedSummary := strings.Join(summaries, "\n\n--- 第%d部分结束 ---\n\n")
+			// -since last 以本次开始展开输入的时间作为下次的起点
+			start := time.Now()
+			var inputs, roots []string
+			batch := len(sources) > 1
+			for _, source := range sources {
+				files, multiple, err := videonote.ExpandInputs(source)
+				if err != nil {
+					return err
+				}
+				inputs = append(inputs, files...)
+				for range files {
+					roots = append(roots, videonote.InputRoot(source))
+				}
+				batch = batch || multiple
+			}
+			// 只有字幕输入时不需要 ffmpeg
+			needFFmpeg := false
+			for _, input := range inputs {
+				if err := videonote.CheckMediaInput(input); err != nil {
+					return err
+				}
+				needFFmpeg = needFFmpeg || !videonote.IsSubtitleFile(input)
+			}
+			if needFFmpeg {
+				if err := videonote.CheckFFmpeg(config); err != nil {
+					return err
+				}
+			}
+			var outputs []string
+			switch {
+			case outputDir != "":
+				if outputPath != "" {
+					return fmt.Errorf("-o 与 -output-dir 不能同时使用")
+				}
+				outputs = videonote.PlanOutputs(inputs, roots, outputDir, opts.Format.Ext())
+			case batch:
+				if outputPath == videonote.StdoutPath {
+					return fmt.Errorf("批量处理时不能将笔记写入标准输出 (-o -)")
+				}
+				outputs = videonote.PlanOutputs(inputs, nil, outputPath, opts.Format.Ext())
+			default:
+				outputs = []string{outputPath}
+			}
 
-	// 写入输出文件
-	if err := os.WriteFile(outputPath, []byte(combinedSummary), 0644); err != nil {
-		return fmt.Errorf("写入摘要文件失败: %w", err)
-	}
+			// 记录已完成的输入，中断后重新运行时跳过；-dry-run 不调用接口，不记录
+			var state *videonote.BatchState
+			if batch && !dryRun && stateFile != "" {
+				if state, err = videonote.LoadBatchState(stateFile, force); err != nil {
+					return err
+				}
+			}
 
-	return nil
-}
+			// 只处理在指定时间之后修改过的文件，笔记路径在筛选前确定，保持与完整运行时一致
+			if since != "" {
+				var t time.Time
+				if since == videonote.SinceLast {
+					if state == nil {
+						return fmt.Errorf("-since last 需要批量处理并启用 -state-file")
+					}
+					if t = state.Since(); t.IsZero() {
+						infof("状态文件中还没有上次运行的记录，处理全部输入")
+					}
+				} else if t, err = videonote.ParseSince(since, start); err != nil {
+					return err
+				}
+
+				kept := videonote.ModifiedSince(inputs, t)
+				if !t.IsZero() {
+					infof("%d 个输入中有 %d 个在 %s 之后修改过", len(inputs), len(kept), t.Local().Format("2006-01-02 15:04:05"))
+				}
+				if len(kept) == 0 {
+					infof("没有需要处理的新文件")
+					return nil
+				}
+				filtered, filteredOutputs := make([]string, len(kept)), make([]string, len(kept))
+				for i, k := range kept {
+					filtered[i], filteredOutputs[i] = inputs[k], outputs[k]
+				}
+				inputs, outputs = filtered, filteredOutputs
+			}
 
-func splitTextIntoChunks(text string, chunkSize int) []string {
-	var chunks []string
-	words := strings.Fields(text)
-	currentChunk := ""
-
-	for _, word := range words {
-		if len(currentChunk)+len(word)+1 > chunkSize {
-			chunks = append(chunks, currentChunk)
-			currentChunk = word
-		} else {
-			if currentChunk == "" {
-				currentChunk = word
-			} else {
-				currentChunk += " " + word
+			if batch {
+				err := videonote.RunBatch(ctx, config, inputs, outputs, jobs, opts, state)
+				// 有失败的文件时不更新运行时间，下次 -since last 仍会考虑它们，已完成的由状态文件跳过
+				if err == nil && state != nil {
+					if err := state.FinishRun(start); err != nil {
+						warnf("写入状态文件失败: %v", err)
+					}
+				}
+				return err
 			}
-		}
+			_, err = videonote.Generate(ctx, config, inputs[0], outputs[0], opts)
+			videonote.FileDone(err)
+			return err
+		},
 	}
 
-	if currentChunk != "" {
-		chunks = append(chunks, currentChunk)
-	}
+	cmd.FlagSet.StringVar(&videoPath, "i", "", "输入视频文件路径、目录、通配符 (如 *.mp4) 或 http(s) 视频链接")
+	cmd.FlagSet.StringVar(&outputPath, "o", "", "输出笔记文件路径 (默认与视频同名，- 表示标准输出)，批量处理或指定多个文件时为输出目录")
+	cmd.FlagSet.StringVar(&outputDir, "output-dir", "", "笔记输出目录，按输入目录的结构保存每个笔记")
+	cmd.FlagSet.StringVar(&outputNames, "outputs", "summary", "逗号分隔的输出 (summary, transcript, srt, vtt)，转录和字幕写在笔记旁边")
+	cmd.FlagSet.StringVar(&outputTmpl, "output-template", "", "转录和字幕的文件名模板，可用 {name} (笔记文件名去掉扩展名)、{kind} 和 {ext} (默认 {name}.transcript.txt、{name}.srt、{name}.vtt)")
+	cmd.FlagSet.StringVar(&stateFile, "state-file", videonote.DefaultStateFile, "批量处理的状态文件，记录已完成的输入以便中断后继续 (为空时不记录)")
+	cmd.FlagSet.StringVar(&since, "since", "", "只处理在该时间之后修改过的文件：last 为上次全部成功的批量处理，或日期 (2024-05-01)、RFC 3339 时间、时长 (24h)")
+	cmd.FlagSet.BoolVar(&force, "force", false, "批量处理时忽略状态文件，重新处理所有输入并覆盖已有笔记")
+	cmd.FlagSet.Float64Var(&summaryRatio, "ratio", videonote.DefaultRatio, "摘要比例 (0.1-0.5)")
+	cmd.FlagSet.DurationVar(&segmentTime, "segment-time", 10*time.Minute, "音频超过25MB时的分段时长，码率较高时自动缩短以保证每段不超过25MB")
+	cmd.FlagSet.StringVar(&formatName, "format", "text", "输出格式 (text, md, json, flashcards, flashcards-csv)")
+	cmd.FlagSet.BoolVar(&timestamps, "timestamps", false, "在每个笔记段落前标注对应的视频时间 [mm:ss]")
+	cmd.FlagSet.StringVar(&cookiesPath, "cookies", "", "下载在线视频时传给yt-dlp的cookies文件")
+	cmd.FlagSet.IntVar(&jobs, "jobs", 2, "批量处理时同时处理的视频数量")
+	cmd.FlagSet.IntVar(&concurrency, "concurrency", videonote.DefaultConcurrency, "同时转录的音频分段和同时生成摘要的文本块数量")
+	cmd.FlagSet.StringVar(&lang, "lang", "", "笔记使用的语言代码，如 zh、en、ja (默认与原文一致)")
+	cmd.FlagSet.BoolVar(&translate, "translate", false, "使用Whisper翻译接口直接将音频转录为英文")
+	cmd.FlagSet.StringVar(&promptFile, "prompt-file", "", "自定义摘要提示词模板文件 (Go text/template，可用 {{.Text}}、{{.Ratio}}、{{.Percent}})")
+	cmd.FlagSet.BoolVar(&quiet, "quiet", false, "不显示处理进度")
+	cmd.FlagSet.BoolVar(&dryRun, "dry-run", false, "只提取音频并预估token用量与费用，不调用转录和摘要接口")
+	cmd.FlagSet.BoolVar(&keepFiles, "keep-intermediate", false, "保留提取的音频和原始转录文本，保存在笔记旁边")
+	cmd.FlagSet.StringVar(&workDir, "work-dir", "", "保存音频和原始转录文本的目录 (不会被清理)")
+	cmd.FlagSet.StringVar(&presetName, "preset", "", "按视频类型使用一组预设的参数 ("+videonote.PresetNames()+")，明确指定的参数优先")
+	cmd.FlagSet.StringVar(&modeName, "mode", "flat", "摘要模式：flat 逐段摘要后拼接，map-reduce 再整合为一份连贯的笔记")
+	cmd.FlagSet.BoolVar(&diarize, "diarize", false, "进行说话人分离，在转录和笔记中标注说话人 (需配置 diarize_command)")
+	cmd.FlagSet.BoolVar(&stream, "stream", false, "在终端实时显示正在生成的摘要 (逐块生成)")
+	cmd.FlagSet.BoolVar(&pipeline, "pipeline", false, "边转录边摘要：每段音频转录完成后立即开始摘要，已完成的部分逐步写入笔记，适合很长的录音")
+	videonote.RegisterSamplingFlags(cmd.FlagSet, config)
+	videonote.RegisterModelsFlag(cmd.FlagSet, config)
+	videonote.RegisterSystemPromptFlag(cmd.FlagSet, config)
+	cmd.FlagSet.BoolVar(&stats, "stats", false, "在标准错误输出原文与摘要字数、实际摘要比例和预计阅读时间")
+	cmd.FlagSet.BoolVar(&overwrite, "overwrite", false, "覆盖已存在的笔记文件")
+	cmd.FlagSet.BoolVar(&noClobber, "no-clobber", false, "笔记文件已存在时跳过该视频")
+	cmd.FlagSet.BoolVar(&withText, "include-transcript", false, "在笔记之后附上完整转录")
+	cmd.FlagSet.IntVar(&overlap, "chunk-overlap", 0, "每个文本块附带前一块结尾的token数，帮助模型理解衔接处的内容")
+	videonote.RegisterChunkSizeFlag(cmd.FlagSet, config)
+	videonote.RegisterPostHookFlag(cmd.FlagSet, config)
+	cmd.FlagSet.BoolVar(&clean, "clean-transcript", false, "生成摘要前清理转录文本：去除语气词和重复词、规范标点，有时间戳时按停顿分段")
+	cmd.FlagSet.BoolVar(&dedup, "dedup", false, "逐段摘要后再请求一次，删除各部分之间重复的要点 (保留分段结构，不能与 -mode map-reduce 同时使用)")
+	cmd.FlagSet.BoolVar(&bom, "bom", false, "在 text/md 笔记开头写入 UTF-8 BOM，避免部分 Windows 编辑器显示乱码")
+	cmd.FlagSet.IntVar(&refine, "refine", 0, "摘要长度偏离 -ratio 的目标超过容差时请求扩写或精简，最多调整的次数 (0 表示不调整)")
+	cmd.FlagSet.Float64Var(&tolerance, "ratio-tolerance", videonote.DefaultRatioTolerance, "-refine 允许的长度偏差，0.25 表示目标长度的 ±25%")
+	cmd.FlagSet.IntVar(&minLength, "min-length", videonote.DefaultMinLength, "转录少于该字数时不生成摘要，直接以转录作为笔记内容 (0 表示总是生成摘要)")
+	cmd.FlagSet.IntVar(&words, "words", 0, "最终笔记的目标字数，指定时代替 -ratio (0 表示按 -ratio)")
+	cmd.FlagSet.StringVar(&extractNames, "extract", "", "逗号分隔的结构化提取项 (topics, entities, actions)，结果附在笔记之后")
+	cmd.FlagSet.BoolVar(&metadata, "metadata", false, "在笔记旁写入同名的 .meta.json，记录来源、时长、模型、语言、分块数和token用量")
+	cmd.FlagSet.BoolVar(&failFast, "fail-fast", false, "遇到第一个错误就停止：不再生成其余部分的摘要，批量处理时不再启动新的文件")
+	cmd.FlagSet.StringVar(&tocName, "toc", "", "在笔记开头生成目录：md 为链接到各部分和视频时间点的目录，youtube 为可粘贴到视频描述的章节时间戳 (自动启用时间戳)")
+	cmd.FlagSet.BoolVar(&extract.TrimSilence, "trim-silence", false, "转录前去除音频中的静音片段，笔记中的时间戳仍对应原视频")
+	cmd.FlagSet.StringVar(&extract.SilenceThreshold, "silence-threshold", "-35dB", "低于该音量视为静音")
+	cmd.FlagSet.DurationVar(&extract.SilenceDuration, "silence-duration", 2*time.Second, "静音持续超过该时长才会被去除")
+	cmd.FlagSet.StringVar(&extract.Codec, "audio-codec", config.AudioCodec, "提取音频使用的编码 (mp3, wav, flac, opus, aac)")
+	cmd.FlagSet.IntVar(&extract.SampleRate, "sample-rate", config.AudioSampleRate, "提取音频的采样率，如 16000 (默认保持原样)")
+	cmd.FlagSet.IntVar(&extract.Channels, "channels", config.AudioChannels, "提取音频的声道数，如 1 (默认保持原样)")
+	cmd.FlagSet.IntVar(&extract.AudioTrack, "audio-track", -1, "提取第几条音轨 (从0开始)，用于多音轨视频，可用 info 命令查看 (默认使用ffmpeg选择的音轨)")
+	cmd.FlagSet.StringVar(&extract.Bitrate, "bitrate", config.AudioBitrate, "提取音频的码率，如 64k (无损编码时无效)")
+	cmd.FlagSet.BoolVar(&extract.Normalize, "normalize", false, "提取音频时用 loudnorm 统一响度，改善音量过小或忽大忽小的录音的识别效果")
+	cmd.FlagSet.Float64Var(&extract.Loudness, "loudness", config.Loudness, "-normalize 的目标响度 (LUFS，-70 到 -5)")
+	cmd.FlagSet.Func("ffmpeg-args", "提取音频时追加的 ffmpeg 输出选项 (高级)，如 \"-af loudnorm\"，不能覆盖输入、输出和编码相关的选项", func(s string) (err error) {
+		extract.ExtraArgs, err = videonote.ParseFFmpegArgs(s)
+		return err
+	})
+	cmd.FlagSet.Func("start", "只处理从该时间开始的部分，如 10:00 或 1:02:03", func(s string) (err error) {
+		extract.Start, err = videonote.ParseTimestamp(s)
+		return err
+	})
+	cmd.FlagSet.Func("end", "只处理到该时间为止的部分，如 25:00 (默认到结尾)", func(s string) (err error) {
+		extract.End, err = videonote.ParseTimestamp(s)
+		return err
+	})
+	cmd.FlagSet.StringVar(&cacheDir, "cache-dir", videonote.DefaultCacheDir(), "转录结果缓存目录")
+	cmd.FlagSet.BoolVar(&noCache, "no-cache", false, "不使用转录缓存，总是重新调用转录接口")
+	cmd.FlagSet.StringVar(&backend, "transcriber", config.Transcriber, "转录后端：openai 调用Whisper接口，local 使用本地 whisper.cpp")
+	cmd.FlagSet.StringVar(&whisperFmt, "whisper-format", "", "Whisper的响应格式 (text, json, verbose_json, srt, vtt)，默认需要时间戳时为 verbose_json，否则为 text")
+	cmd.FlagSet.Float64Var(&whisperTemp, "whisper-temperature", 0, "Whisper的采样温度 (0-1)，为 0 时使用接口默认值")
+	cmd.FlagSet.Float64Var(&confidence, "confidence-threshold", videonote.DefaultConfidenceThreshold, fmt.Sprintf("转录片段的 avg_logprob 低于该值时提示置信度低的时间段，如 %g (0 表示不检查，只有 whisper-1 返回置信度)", videonote.SuggestedConfidenceThreshold))
+	cmd.FlagSet.StringVar(&sourceLang, "source-lang", "", "视频原声的语言代码 (ISO-639-1，如 zh、en)，默认自动识别")
+	cmd.FlagSet.StringVar(&hint, "prompt", "", "传给Whisper的提示文本，用于提高人名、术语等的识别准确率")
+	cmd.FlagSet.StringVar(&glossaryFile, "glossary", "", "术语表文件，每行一个术语：作为Whisper的提示，更正转录中术语的大小写、拆开的写法和拼写错误，并要求摘要按此拼写")
 
-	return chunks
+	return cmd
 }
 
-func transcribeCommand(config *Config) *ffcli.Command {
+func transcribeCommand(config *videonote.Config) *ffcli.Command {
 	var (
-		audioPath  string
-		outputPath string
+		audioPath    string
+		outputPath   string
+		segmentTime  time.Duration
+		formatName   string
+		quiet        bool
+		diarize      bool
+		cacheDir     string
+		noCache      bool
+		backend      string
+		whisperFmt   string
+		whisperTemp  float64
+		confidence   float64
+		sourceLang   string
+		hint         string
+		glossaryFile string
+		overwrite    bool
+		noClobber    bool
+		concurrency  int
 	)
 
 	cmd := &ffcli.Command{
@@ -293,67 +570,313 @@ func transcribeCommand(config *Config) *ffcli.Command {
 			if audioPath == "" {
 				return fmt.Errorf("必须指定音频文件 (-i)")
 			}
+			if err := videonote.CheckAudioInput(audioPath); err != nil {
+				return err
+			}
+			if err := videonote.ValidateSourceLanguage(sourceLang); err != nil {
+				return err
+			}
+
+			format, err := videonote.ParseTranscriptFormat(formatName)
+			if err != nil {
+				return err
+			}
 
 			if outputPath == "" {
 				ext := filepath.Ext(audioPath)
-				outputPath = strings.TrimSuffix(audioPath, ext) + ".txt"
+				outputPath = strings.TrimSuffix(audioPath, ext) + format.Ext()
+			}
+			policy, err := videonote.OverwritePolicy(overwrite, noClobber)
+			if err != nil {
+				return err
+			}
+			if skip, err := videonote.CheckOutput(outputPath, policy); err != nil || skip {
+				return err
+			}
+
+			glossary, err := videonote.LoadGlossary(glossaryFile)
+			if err != nil {
+				return err
 			}
 
-			log.Printf("正在将音频转换为文字...")
-			if err := transcribeAudio(ctx, config.OpenAIAPIKey, config.Model, audioPath, outputPath); err != nil {
+			infof("正在将音频转换为文字...")
+			opts := videonote.TranscribeOptions{
+				SegmentTime:         segmentTime,
+				Format:              format,
+				Quiet:               quiet,
+				Diarize:             diarize,
+				Language:            sourceLang,
+				Prompt:              hint,
+				Backend:             backend,
+				ResponseFormat:      whisperFmt,
+				Temperature:         float32(whisperTemp),
+				Concurrency:         concurrency,
+				ConfidenceThreshold: confidence,
+				Glossary:            glossary,
+			}
+			if err := videonote.ValidateWhisperOptions(whisperFmt, whisperTemp); err != nil {
+				return err
+			}
+			if confidence > 0 {
+				return fmt.Errorf("-confidence-threshold 不能为正数 (avg_logprob 不大于 0)")
+			}
+			if !noCache {
+				opts.CacheDir = cacheDir
+			}
+			_, err = videonote.Transcribe(ctx, config, audioPath, outputPath, opts)
+			videonote.FileDone(err)
+			if err != nil {
 				return fmt.Errorf("音频转文字失败: %w", err)
 			}
 
-			log.Printf("转录完成: %s", outputPath)
+			infof("转录完成: %s", outputPath)
 			return nil
 		},
 	}
 
 	cmd.FlagSet.StringVar(&audioPath, "i", "", "输入音频文件路径")
-	cmd.FlagSet.StringVar(&outputPath, "o", "", "输出转录文件路径 (默认与音频同名)")
+	cmd.FlagSet.StringVar(&outputPath, "o", "", "输出转录文件路径 (默认与音频同名，- 表示标准输出)")
+	cmd.FlagSet.DurationVar(&segmentTime, "segment-time", 10*time.Minute, "音频超过25MB时的分段时长，码率较高时自动缩短以保证每段不超过25MB")
+	cmd.FlagSet.StringVar(&formatName, "format", "txt", "转录输出格式 (txt, srt, vtt)")
+	cmd.FlagSet.BoolVar(&quiet, "quiet", false, "不显示处理进度")
+	cmd.FlagSet.BoolVar(&diarize, "diarize", false, "进行说话人分离，在转录中标注说话人 (需配置 diarize_command)")
+	cmd.FlagSet.StringVar(&cacheDir, "cache-dir", videonote.DefaultCacheDir(), "转录结果缓存目录")
+	cmd.FlagSet.BoolVar(&noCache, "no-cache", false, "不使用转录缓存，总是重新调用转录接口")
+	cmd.FlagSet.StringVar(&backend, "transcriber", config.Transcriber, "转录后端：openai 调用Whisper接口，local 使用本地 whisper.cpp")
+	cmd.FlagSet.StringVar(&whisperFmt, "whisper-format", "", "Whisper的响应格式 (text, json, verbose_json, srt, vtt)，默认需要时间戳时为 verbose_json，否则为 text")
+	cmd.FlagSet.Float64Var(&whisperTemp, "whisper-temperature", 0, "Whisper的采样温度 (0-1)，为 0 时使用接口默认值")
+	cmd.FlagSet.Float64Var(&confidence, "confidence-threshold", videonote.DefaultConfidenceThreshold, fmt.Sprintf("转录片段的 avg_logprob 低于该值时提示置信度低的时间段，如 %g (0 表示不检查，只有 whisper-1 返回置信度)", videonote.SuggestedConfidenceThreshold))
+	cmd.FlagSet.StringVar(&sourceLang, "source-lang", "", "音频的语言代码 (ISO-639-1，如 zh、en)，默认自动识别")
+	cmd.FlagSet.StringVar(&hint, "prompt", "", "传给Whisper的提示文本，用于提高人名、术语等的识别准确率")
+	cmd.FlagSet.StringVar(&glossaryFile, "glossary", "", "术语表文件，每行一个术语：作为Whisper的提示，并更正转录中术语的大小写、拆开的写法和拼写错误")
+	cmd.FlagSet.BoolVar(&overwrite, "overwrite", false, "覆盖已存在的转录文件")
+	cmd.FlagSet.BoolVar(&noClobber, "no-clobber", false, "转录文件已存在时跳过")
+	cmd.FlagSet.IntVar(&concurrency, "concurrency", videonote.DefaultConcurrency, "音频切分后同时转录的分段数量")
 
 	return cmd
 }
 
-func summarizeCommand(config *Config) *ffcli.Command {
+func summarizeCommand(config *videonote.Config) *ffcli.Command {
 	var (
 		inputPath    string
 		outputPath   string
 		summaryRatio float64
+		formatName   string
+		concurrency  int
+		lang         string
+		promptFile   string
+		quiet        bool
+		dryRun       bool
+		modeName     string
+		stream       bool
+		stats        bool
+		overwrite    bool
+		noClobber    bool
+		overlap      int
+		clean        bool
+		dedup        bool
+		bom          bool
+		refine       int
+		tolerance    float64
+		words        int
+		extractNames string
+		metadata     bool
+		failFast     bool
+		minLength    int
+		glossaryFile string
+		presetName   string
 	)
 
+	fs := flag.NewFlagSet("video-note summarize", flag.ExitOnError)
 	cmd := &ffcli.Command{
 		Name:       "summarize",
 		ShortUsage: "video-note summarize [flags] -i transcript.txt -o summary.txt",
 		ShortHelp:  "从文本生成摘要笔记",
-		FlagSet:    flag.NewFlagSet("video-note summarize", flag.ExitOnError),
+		FlagSet:    fs,
 		Exec: func(ctx context.Context, args []string) error {
+			if err := videonote.ApplyPreset(fs, config, presetName); err != nil {
+				return err
+			}
+			// 未指定 -i 且有管道输入时从标准输入读取
+			if inputPath == "" && !videonote.IsTerminal(os.Stdin) {
+				inputPath = videonote.StdinPath
+			}
 			if inputPath == "" {
-				return fmt.Errorf("必须指定输入文本文件 (-i)")
+				return fmt.Errorf("必须指定输入文本文件 (-i，- 表示标准输入)")
 			}
 
-			if outputPath == "" {
+			format, err := videonote.ParseFormat(formatName)
+			if err != nil {
+				return err
+			}
+
+			prompt, err := videonote.LoadPromptTemplate(promptFile)
+			if err != nil {
+				return err
+			}
+
+			mode, err := videonote.ParseMode(modeName)
+			if err != nil {
+				return err
+			}
+			if err := videonote.CheckModeFormat(mode, format); err != nil {
+				return err
+			}
+			if dedup && mode == videonote.ModeMapReduce {
+				return fmt.Errorf("-dedup 不能与 -mode map-reduce 同时使用，整合摘要时已会合并重复的要点")
+			}
+			if bom {
+				if err := videonote.CheckBOM(format); err != nil {
+					return err
+				}
+			}
+			if refine < 0 || tolerance <= 0 {
+				return fmt.Errorf("-refine 不能为负数，-ratio-tolerance 必须大于 0")
+			}
+			if minLength < 0 {
+				return fmt.Errorf("-min-length 不能为负数")
+			}
+			if err := videonote.CheckRatio(summaryRatio); err != nil {
+				return err
+			}
+			if err := videonote.CheckWords(words, format); err != nil {
+				return err
+			}
+			extractors, err := videonote.ParseExtractors(extractNames)
+			if err != nil {
+				return err
+			}
+			if err := videonote.CheckExtract(extractors, format); err != nil {
+				return err
+			}
+			glossary, err := videonote.LoadGlossary(glossaryFile)
+			if err != nil {
+				return err
+			}
+
+			if err := config.ValidateChunkOverlap(overlap); err != nil {
+				return err
+			}
+
+			var input io.Reader = os.Stdin
+			title, source := "笔记", "stdin"
+			if inputPath == videonote.StdinPath {
+				// 标准输入没有文件名可以推断输出路径，默认写入标准输出
+				if outputPath == "" {
+					outputPath = videonote.StdoutPath
+				}
+			} else {
+				if err := videonote.CheckTextInput(inputPath); err != nil {
+					return err
+				}
+				f, err := os.Open(inputPath)
+				if err != nil {
+					return fmt.Errorf("打开转录文件失败: %w", err)
+				}
+				defer f.Close()
+				input = f
+
 				ext := filepath.Ext(inputPath)
-				outputPath = strings.TrimSuffix(inputPath, ext) + ".summary.txt"
+				title, source = strings.TrimSuffix(filepath.Base(inputPath), ext), inputPath
+				if outputPath == "" {
+					outputPath = strings.TrimSuffix(inputPath, ext) + ".summary" + format.Ext()
+				}
+			}
+
+			if dryRun {
+				text, err := io.ReadAll(input)
+				if err != nil {
+					return fmt.Errorf("读取转录文本失败: %w", err)
+				}
+				transcript, err := videonote.ParseTranscriptText(string(text))
+				if err != nil {
+					return err
+				}
+				if words > 0 {
+					summaryRatio = videonote.WordsRatio(transcript.Text, words)
+				}
+				videonote.EstimateFromText(config, transcript, summaryRatio).Print(os.Stdout)
+				return nil
 			}
 
-			if summaryRatio < 0.1 || summaryRatio > 0.5 {
-				return fmt.Errorf("摘要比例必须在0.1-0.5之间")
+			policy, err := videonote.OverwritePolicy(overwrite, noClobber)
+			if err != nil {
+				return err
+			}
+			if skip, err := videonote.CheckOutput(outputPath, policy); err != nil || skip {
+				return err
 			}
 
-			log.Printf("正在生成笔记摘要...")
-			if err := summarizeText(ctx, config.OpenAIAPIKey, config.Model, inputPath, outputPath, summaryRatio); err != nil {
+			infof("正在生成笔记摘要...")
+			opts := videonote.SummarizeOptions{
+				Ratio:           summaryRatio,
+				Format:          format,
+				Mode:            mode,
+				Prompt:          prompt,
+				Title:           title,
+				Concurrency:     concurrency,
+				Language:        lang,
+				Quiet:           quiet,
+				Stream:          stream,
+				Stats:           stats,
+				Source:          source,
+				ChunkOverlap:    overlap,
+				CleanTranscript: clean,
+				Dedup:           dedup,
+				BOM:             bom,
+				Refine:          refine,
+				RatioTolerance:  tolerance,
+				Words:           words,
+				Extractors:      extractors,
+				Metadata:        metadata,
+				FailFast:        failFast,
+				MinLength:       minLength,
+				Glossary:        glossary,
+				Preset:          presetName,
+			}
+			err = videonote.Summarize(ctx, config, input, outputPath, opts)
+			videonote.FileDone(err)
+			if err != nil {
 				return fmt.Errorf("生成摘要失败: %w", err)
 			}
 
-			log.Printf("摘要已生成: %s", outputPath)
+			infof("摘要已生成: %s", outputPath)
 			return nil
 		},
 	}
 
-	cmd.FlagSet.StringVar(&inputPath, "i", "", "输入文本文件路径")
-	cmd.FlagSet.StringVar(&outputPath, "o", "", "输出摘要文件路径 (默认与输入同名)")
-	cmd.FlagSet.Float64Var(&summaryRatio, "ratio", 0.2, "摘要比例 (0.1-0.5)")
+	cmd.FlagSet.StringVar(&inputPath, "i", "", "输入文本文件路径，- 表示标准输入 (有管道输入时可省略)")
+	cmd.FlagSet.StringVar(&outputPath, "o", "", "输出摘要文件路径 (默认与输入同名，- 表示标准输出)")
+	cmd.FlagSet.Float64Var(&summaryRatio, "ratio", videonote.DefaultRatio, "摘要比例 (0.1-0.5)")
+	cmd.FlagSet.StringVar(&formatName, "format", "text", "输出格式 (text, md, json, flashcards, flashcards-csv)")
+	cmd.FlagSet.IntVar(&concurrency, "concurrency", videonote.DefaultConcurrency, "同时生成摘要的文本块数量")
+	cmd.FlagSet.StringVar(&lang, "lang", "", "笔记使用的语言代码，如 zh、en、ja (默认与原文一致)")
+	cmd.FlagSet.StringVar(&promptFile, "prompt-file", "", "自定义摘要提示词模板文件 (Go text/template，可用 {{.Text}}、{{.Ratio}}、{{.Percent}})")
+	cmd.FlagSet.BoolVar(&quiet, "quiet", false, "不显示处理进度")
+	cmd.FlagSet.BoolVar(&dryRun, "dry-run", false, "只预估token用量与费用，不调用摘要接口")
+	cmd.FlagSet.StringVar(&presetName, "preset", "", "按视频类型使用一组预设的参数 ("+videonote.PresetNames()+")，明确指定的参数优先")
+	cmd.FlagSet.StringVar(&modeName, "mode", "flat", "摘要模式：flat 逐段摘要后拼接，map-reduce 再整合为一份连贯的笔记")
+	cmd.FlagSet.BoolVar(&stream, "stream", false, "在终端实时显示正在生成的摘要 (逐块生成)")
+	videonote.RegisterSamplingFlags(cmd.FlagSet, config)
+	videonote.RegisterModelsFlag(cmd.FlagSet, config)
+	videonote.RegisterSystemPromptFlag(cmd.FlagSet, config)
+	cmd.FlagSet.BoolVar(&stats, "stats", false, "在标准错误输出原文与摘要字数、实际摘要比例和预计阅读时间")
+	cmd.FlagSet.BoolVar(&overwrite, "overwrite", false, "覆盖已存在的摘要文件")
+	cmd.FlagSet.BoolVar(&noClobber, "no-clobber", false, "摘要文件已存在时跳过")
+	cmd.FlagSet.IntVar(&overlap, "chunk-overlap", 0, "每个文本块附带前一块结尾的token数，帮助模型理解衔接处的内容")
+	videonote.RegisterChunkSizeFlag(cmd.FlagSet, config)
+	videonote.RegisterPostHookFlag(cmd.FlagSet, config)
+	cmd.FlagSet.BoolVar(&clean, "clean-transcript", false, "生成摘要前清理转录文本：去除语气词和重复词、规范标点，有时间戳时按停顿分段")
+	cmd.FlagSet.BoolVar(&dedup, "dedup", false, "逐段摘要后再请求一次，删除各部分之间重复的要点 (保留分段结构，不能与 -mode map-reduce 同时使用)")
+	cmd.FlagSet.BoolVar(&bom, "bom", false, "在 text/md 笔记开头写入 UTF-8 BOM，避免部分 Windows 编辑器显示乱码")
+	cmd.FlagSet.IntVar(&refine, "refine", 0, "摘要长度偏离 -ratio 的目标超过容差时请求扩写或精简，最多调整的次数 (0 表示不调整)")
+	cmd.FlagSet.Float64Var(&tolerance, "ratio-tolerance", videonote.DefaultRatioTolerance, "-refine 允许的长度偏差，0.25 表示目标长度的 ±25%")
+	cmd.FlagSet.IntVar(&minLength, "min-length", videonote.DefaultMinLength, "转录少于该字数时不生成摘要，直接以转录作为笔记内容 (0 表示总是生成摘要)")
+	cmd.FlagSet.IntVar(&words, "words", 0, "最终笔记的目标字数，指定时代替 -ratio (0 表示按 -ratio)")
+	cmd.FlagSet.StringVar(&extractNames, "extract", "", "逗号分隔的结构化提取项 (topics, entities, actions)，结果附在笔记之后")
+	cmd.FlagSet.BoolVar(&metadata, "metadata", false, "在笔记旁写入同名的 .meta.json，记录来源、时长、模型、语言、分块数和token用量")
+	cmd.FlagSet.BoolVar(&failFast, "fail-fast", false, "遇到第一个错误就停止，不再生成其余部分的摘要")
+	cmd.FlagSet.StringVar(&glossaryFile, "glossary", "", "术语表文件，每行一个术语：更正转录中术语的大小写、拆开的写法和拼写错误，并要求摘要按此拼写")
 
 	return cmd
-}    
\ No newline at end of file
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cimile/ai-video-note-generator/videonote"
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+// 任务状态
+const (
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// job 为一次异步生成笔记的任务
+type job struct {
+	ID       string     `json:"id"`
+	Status   string     `json:"status"`
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	dir      string
+	notePath string
+}
+
+// server 通过HTTP提供生成笔记的接口，同时运行的任务数受 slots 限制
+type server struct {
+	config    *videonote.Config
+	dir       string
+	maxUpload int64
+	ttl       time.Duration
+	slots     chan struct{}
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+func serveCommand(config *videonote.Config) *ffcli.Command {
+	var (
+		addr      string
+		dir       string
+		maxUpload int64
+		maxJobs   int
+		ttl       time.Duration
+	)
+
+	cmd := &ffcli.Command{
+		Name:       "serve",
+		ShortUsage: "video-note serve [flags]",
+		ShortHelp:  "启动HTTP服务，通过接口上传视频并生成笔记",
+		FlagSet:    flag.NewFlagSet("video-note serve", flag.ExitOnError),
+		Exec: func(ctx context.Context, args []string) error {
+			if maxJobs < 1 {
+				return fmt.Errorf("-max-jobs 必须大于 0")
+			}
+			if maxUpload < 1 {
+				return fmt.Errorf("-max-upload 必须大于 0")
+			}
+			if err := videonote.CheckFFmpeg(config); err != nil {
+				return err
+			}
+			if dir == "" {
+				dir = filepath.Join(os.TempDir(), "video-note-serve")
+			}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("创建工作目录失败: %w", err)
+			}
+
+			s := &server{
+				config:    config,
+				dir:       dir,
+				maxUpload: maxUpload << 20,
+				ttl:       ttl,
+				slots:     make(chan struct{}, maxJobs),
+				jobs:      make(map[string]*job),
+			}
+			mux := http.NewServeMux()
+			mux.HandleFunc("/notes", s.handleNotes(ctx))
+			mux.HandleFunc("/jobs/", s.handleJob)
+
+			srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				srv.Shutdown(shutdownCtx)
+			}()
+			go s.sweep(ctx)
+
+			infof("HTTP服务已启动: %s", addr)
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("HTTP服务异常退出: %w", err)
+			}
+			return ctx.Err()
+		},
+	}
+
+	cmd.FlagSet.StringVar(&addr, "addr", "127.0.0.1:8080", "监听地址")
+	cmd.FlagSet.StringVar(&dir, "dir", "", "保存上传文件和笔记的目录 (默认使用系统临时目录)")
+	cmd.FlagSet.Int64Var(&maxUpload, "max-upload", 500, "单个上传文件的大小上限 (MB)")
+	cmd.FlagSet.IntVar(&maxJobs, "max-jobs", 2, "同时处理的任务数量上限，超出时返回 429")
+	cmd.FlagSet.DurationVar(&ttl, "job-ttl", time.Hour, "异步任务完成后保留结果的时长")
+	videonote.RegisterSamplingFlags(cmd.FlagSet, config)
+	videonote.RegisterModelsFlag(cmd.FlagSet, config)
+	videonote.RegisterSystemPromptFlag(cmd.FlagSet, config)
+
+	return cmd
+}
+
+// handleNotes 处理 POST /notes：multipart 表单字段 file 为上传的视频或音频，
+// 查询参数 format、lang、ratio、mode、timestamps 与 generate 的同名参数一致；
+// async=1 时立即返回任务ID，否则等待生成完成后直接返回笔记
+func (s *server) handleNotes(base context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			httpError(w, http.StatusMethodNotAllowed, "只支持 POST")
+			return
+		}
+
+		opts, err := s.options(r)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// 先占用任务名额再接收上传，名额已满时不必读取整个文件
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			httpError(w, http.StatusTooManyRequests, "同时处理的任务过多，请稍后重试")
+			return
+		}
+		release := func() { <-s.slots }
+
+		j, inputPath, err := s.receive(w, r, opts)
+		if err != nil {
+			release()
+			status := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			httpError(w, status, err.Error())
+			return
+		}
+
+		if async, _ := strconv.ParseBool(r.URL.Query().Get("async")); !async {
+			defer release()
+			defer os.RemoveAll(j.dir)
+			if _, err := videonote.Generate(r.Context(), s.config, inputPath, j.notePath, opts); err != nil {
+				errorf("生成笔记失败: %v", err)
+				status := http.StatusInternalServerError
+				if errors.Is(err, videonote.ErrNoSpeech) || errors.Is(err, videonote.ErrMedia) {
+					status = http.StatusUnprocessableEntity
+				}
+				httpError(w, status, err.Error())
+				return
+			}
+			s.serveNote(w, r, j.notePath)
+			return
+		}
+
+		s.mu.Lock()
+		s.jobs[j.ID] = j
+		accepted := *j
+		s.mu.Unlock()
+		go func() {
+			defer release()
+			_, err := videonote.Generate(base, s.config, inputPath, j.notePath, opts)
+			os.Remove(inputPath)
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			now := time.Now()
+			j.Finished = &now
+			if err != nil {
+				errorf("任务 %s 失败: %v", j.ID, err)
+				j.Status, j.Error = jobFailed, err.Error()
+				return
+			}
+			infof("任务 %s 已完成", j.ID)
+			j.Status = jobDone
+		}()
+
+		w.Header().Set("Location", "/jobs/"+j.ID)
+		writeJSON(w, http.StatusAccepted, accepted)
+	}
+}
+
+// options 按查询参数构造生成选项，未指定的参数使用与 generate 相同的默认值
+func (s *server) options(r *http.Request) (videonote.GenerateOptions, error) {
+	query := r.URL.Query()
+	get := func(key, def string) string {
+		if v := query.Get(key); v != "" {
+			return v
+		}
+		return def
+	}
+
+	// 与 generate 命令的默认参数一致
+	opts := videonote.GenerateOptions{
+		Concurrency:         videonote.DefaultConcurrency,
+		Language:            get("lang", ""),
+		SegmentTime:         10 * time.Minute,
+		MinLength:           videonote.DefaultMinLength,
+		RatioTolerance:      videonote.DefaultRatioTolerance,
+		ConfidenceThreshold: videonote.DefaultConfidenceThreshold,
+		Quiet:               true,
+		Extract: videonote.ExtractOptions{
+			SilenceThreshold: "-35dB",
+			SilenceDuration:  2 * time.Second,
+			Codec:            s.config.AudioCodec,
+			SampleRate:       s.config.AudioSampleRate,
+			Channels:         s.config.AudioChannels,
+			Bitrate:          s.config.AudioBitrate,
+			Loudness:         s.config.Loudness,
+			AudioTrack:       -1,
+		},
+		CacheDir:    videonote.DefaultCacheDir(),
+		Transcriber: s.config.Transcriber,
+	}
+
+	var err error
+	if opts.Format, err = videonote.ParseFormat(get("format", "text")); err != nil {
+		return opts, err
+	}
+	if opts.Mode, err = videonote.ParseMode(get("mode", "flat")); err != nil {
+		return opts, err
+	}
+	if err := videonote.CheckModeFormat(opts.Mode, opts.Format); err != nil {
+		return opts, err
+	}
+	if opts.Ratio, err = strconv.ParseFloat(get("ratio", strconv.FormatFloat(videonote.DefaultRatio, 'g', -1, 64)), 64); err != nil {
+		return opts, fmt.Errorf("无效的 ratio: %w", err)
+	}
+	if err := videonote.CheckRatio(opts.Ratio); err != nil {
+		return opts, err
+	}
+	if opts.Timestamps, err = strconv.ParseBool(get("timestamps", "false")); err != nil {
+		return opts, fmt.Errorf("无效的 timestamps: %w", err)
+	}
+	return opts, nil
+}
+
+// receive 将上传的文件保存到新任务的目录中，返回任务和文件路径
+func (s *server) receive(w http.ResponseWriter, r *http.Request, opts videonote.GenerateOptions) (*job, string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUpload)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", fmt.Errorf("读取上传文件失败: %w", err)
+	}
+	defer file.Close()
+
+	name := filepath.Base(header.Filename)
+	if err := videonote.CheckMediaInput(name); err != nil {
+		return nil, "", err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, "", err
+	}
+	j := &job{ID: id, Status: jobRunning, Created: time.Now(), dir: filepath.Join(s.dir, id)}
+	j.notePath = filepath.Join(j.dir, "note"+opts.Format.Ext())
+	if err := os.MkdirAll(j.dir, 0755); err != nil {
+		return nil, "", fmt.Errorf("创建任务目录失败: %w", err)
+	}
+
+	inputPath := filepath.Join(j.dir, name)
+	out, err := os.Create(inputPath)
+	if err == nil {
+		_, err = io.Copy(out, file)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		os.RemoveAll(j.dir)
+		return nil, "", fmt.Errorf("保存上传文件失败: %w", err)
+	}
+	return j, inputPath, nil
+}
+
+// handleJob 处理 GET /jobs/{id} 查询任务状态，GET /jobs/{id}/note 下载生成的笔记
+func (s *server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		httpError(w, http.StatusMethodNotAllowed, "只支持 GET")
+		return
+	}
+	id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+
+	s.mu.Lock()
+	j, ok := s.jobs[id]
+	var snapshot job
+	if ok {
+		snapshot = *j
+	}
+	s.mu.Unlock()
+	if !ok {
+		httpError(w, http.StatusNotFound, "任务不存在或已过期")
+		return
+	}
+
+	switch rest {
+	case "":
+		writeJSON(w, http.StatusOK, snapshot)
+	case "note":
+		if snapshot.Status != jobDone {
+			httpError(w, http.StatusConflict, "任务尚未完成: "+snapshot.Status)
+			return
+		}
+		s.serveNote(w, r, snapshot.notePath)
+	default:
+		httpError(w, http.StatusNotFound, "未知的路径")
+	}
+}
+
+func (s *server) serveNote(w http.ResponseWriter, r *http.Request, path string) {
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	http.ServeFile(w, r, path)
+}
+
+// sweep 定期删除完成超过 ttl 的任务及其文件
+func (s *server) sweep(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for id, j := range s.jobs {
+				if j.Finished != nil && now.Sub(*j.Finished) > s.ttl {
+					os.RemoveAll(j.dir)
+					delete(s.jobs, id)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成任务ID失败: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func httpError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
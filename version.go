@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+// 构建信息，发布时通过 -ldflags 注入，例如：
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse --short HEAD) -X main.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+func versionCommand() *ffcli.Command {
+	return &ffcli.Command{
+		Name:       "version",
+		ShortUsage: "video-note version",
+		ShortHelp:  "显示版本和构建信息",
+		FlagSet:    flag.NewFlagSet("video-note version", flag.ExitOnError),
+		Exec: func(ctx context.Context, args []string) error {
+			printVersion(os.Stdout)
+			return nil
+		},
+	}
+}
+
+// printVersion 输出版本信息；未通过 -ldflags 注入时尽量从 go 构建信息中读取提交和时间
+func printVersion(w io.Writer) {
+	rev, built := commit, date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if rev == "" {
+					rev = setting.Value
+				}
+			case "vcs.time":
+				if built == "" {
+					built = setting.Value
+				}
+			}
+		}
+	}
+	if rev == "" {
+		rev = "unknown"
+	}
+	if built == "" {
+		built = "unknown"
+	}
+
+	fmt.Fprintf(w, "video-note %s\n", version)
+	fmt.Fprintf(w, "commit: %s\n", rev)
+	fmt.Fprintf(w, "built:  %s\n", built)
+	fmt.Fprintf(w, "go:     %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,160 @@
+package videonote
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Artifact 为 generate 一次运行可以写入的一种输出
+type Artifact string
+
+const (
+	ArtifactSummary    Artifact = "summary"
+	ArtifactTranscript Artifact = "transcript"
+	ArtifactSRT        Artifact = "srt"
+	ArtifactVTT        Artifact = "vtt"
+)
+
+// artifactFormats 为转录类输出对应的格式
+var artifactFormats = map[Artifact]Format{
+	ArtifactTranscript: FormatText,
+	ArtifactSRT:        FormatSRT,
+	ArtifactVTT:        FormatVTT,
+}
+
+// DefaultOutputTemplate 为转录类输出的默认文件名，与 -keep-intermediate 保存的转录一致；
+// 字幕使用 {name}.srt 这样的常见命名，便于播放器自动加载
+const DefaultOutputTemplate = "{name}.{kind}.{ext}"
+
+// ParseOutputs 解析逗号分隔的 -outputs，忽略重复项并保持顺序
+func ParseOutputs(s string) ([]Artifact, error) {
+	var outputs []Artifact
+	seen := make(map[Artifact]bool)
+	for _, name := range strings.Split(s, ",") {
+		a := Artifact(strings.ToLower(strings.TrimSpace(name)))
+		if a == "" || seen[a] {
+			continue
+		}
+		if _, ok := artifactFormats[a]; !ok && a != ArtifactSummary {
+			return nil, fmt.Errorf("不支持的输出: %s (可选: summary, transcript, srt, vtt)", name)
+		}
+		seen[a] = true
+		outputs = append(outputs, a)
+	}
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("-outputs 不能为空")
+	}
+	return outputs, nil
+}
+
+// CheckOutputTemplate 检查 -output-template 包含 {name}，否则所有输入的文件会互相覆盖
+func CheckOutputTemplate(tmpl string) error {
+	if tmpl != "" && !strings.Contains(tmpl, "{name}") {
+		return fmt.Errorf("-output-template 必须包含 {name}")
+	}
+	if strings.ContainsAny(tmpl, `/\`) {
+		return fmt.Errorf("-output-template 只能是文件名，不能包含目录")
+	}
+	return nil
+}
+
+// hasArtifact 判断是否请求了该输出；未指定 -outputs 时只生成笔记
+func hasArtifact(outputs []Artifact, a Artifact) bool {
+	if len(outputs) == 0 {
+		return a == ArtifactSummary
+	}
+	for _, o := range outputs {
+		if o == a {
+			return true
+		}
+	}
+	return false
+}
+
+// needsSubtitles 判断是否请求了字幕，字幕需要片段时间戳
+func needsSubtitles(outputs []Artifact) bool {
+	return hasArtifact(outputs, ArtifactSRT) || hasArtifact(outputs, ArtifactVTT)
+}
+
+// checkSubtitleModel 在提取音频之前检查转录模型，避免转录之后才发现模型不返回字幕需要的时间戳
+func checkSubtitleModel(config *Config, opts GenerateOptions) error {
+	if !needsSubtitles(opts.Outputs) || (opts.Transcriber != "" && opts.Transcriber != transcriberOpenAI) {
+		return nil
+	}
+	if !transcribeCapabilities(config.TranscribeModel).timestamps {
+		return fmt.Errorf("转录模型 %s 不返回时间戳，不能输出字幕，请使用 %s", config.TranscribeModel, openai.Whisper1)
+	}
+	return nil
+}
+
+// checkOutputs 按策略检查笔记和各输出文件，返回仍需写入的输出；-no-clobber 时已存在的
+// 输出从 paths 中移除，只补全缺失的部分
+func checkOutputs(outputs []Artifact, notePath string, paths map[Artifact]string, policy Overwrite) ([]Artifact, error) {
+	var remaining []Artifact
+	for _, a := range outputs {
+		path := notePath
+		if a != ArtifactSummary {
+			path = paths[a]
+		}
+		skip, err := CheckOutput(path, policy)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			delete(paths, a)
+			continue
+		}
+		remaining = append(remaining, a)
+	}
+	return remaining, nil
+}
+
+// artifactPaths 返回各转录类输出的路径，写在笔记旁边，文件名由 tmpl 中的 {name} (笔记去掉扩展名)、
+// {kind} 和 {ext} 替换得到；笔记写入标准输出时以输入文件名写在当前目录
+func artifactPaths(outputs []Artifact, notePath, inputPath, tmpl string) map[Artifact]string {
+	base := strings.TrimSuffix(notePath, filepath.Ext(notePath))
+	if notePath == StdoutPath {
+		base = strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	}
+	dir, name := filepath.Split(base)
+
+	paths := make(map[Artifact]string)
+	for _, a := range outputs {
+		format, ok := artifactFormats[a]
+		if !ok {
+			continue
+		}
+		ext := strings.TrimPrefix(format.Ext(), ".")
+		file := tmpl
+		if file == "" {
+			file = DefaultOutputTemplate
+			if a != ArtifactTranscript {
+				file = "{name}.{ext}"
+			}
+		}
+		file = strings.NewReplacer("{name}", name, "{kind}", string(a), "{ext}", ext).Replace(file)
+		paths[a] = filepath.Join(dir, file)
+	}
+	return paths
+}
+
+// writeArtifacts 写入转录、字幕等输出；每个文件先写入临时文件再重命名，不会留下写了一半的文件
+func writeArtifacts(transcript *Transcript, paths map[Artifact]string) error {
+	if len(transcript.Segments) == 0 && (paths[ArtifactSRT] != "" || paths[ArtifactVTT] != "") {
+		return fmt.Errorf("转录结果没有片段时间戳，不能输出字幕")
+	}
+	for _, a := range []Artifact{ArtifactTranscript, ArtifactSRT, ArtifactVTT} {
+		path, ok := paths[a]
+		if !ok {
+			continue
+		}
+		if err := writeOutput(path, []byte(renderTranscript(transcript, artifactFormats[a]))); err != nil {
+			return fmt.Errorf("写入%s失败: %w", a, err)
+		}
+		infof("已写入 %s: %s", a, path)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package videonote
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAI 转录接口对单个音频文件的大小限制
+const maxAudioFileSize = 25 * 1024 * 1024
+
+// 相邻分段之间的重叠时长，避免单词在分段边界处被截断
+const segmentOverlap = 2 * time.Second
+
+// ffmpegBinary 返回 ffmpeg 可执行文件路径，未配置时从 PATH 中查找
+func (c *Config) ffmpegBinary() string {
+	if c.FFmpegPath != "" {
+		return c.FFmpegPath
+	}
+	return "ffmpeg"
+}
+
+// ffprobeBinary 返回与 ffmpeg 同目录的 ffprobe
+func (c *Config) ffprobeBinary() string {
+	if c.FFmpegPath == "" {
+		return "ffprobe"
+	}
+	name := "ffprobe"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(filepath.Dir(c.FFmpegPath), name)
+}
+
+// CheckFFmpeg 确认 ffmpeg 与 ffprobe 可用，否则给出安装指引
+func CheckFFmpeg(config *Config) error {
+	for _, bin := range []string{config.ffmpegBinary(), config.ffprobeBinary()} {
+		if _, err := exec.LookPath(bin); err != nil {
+			return markError(ErrDependency, fmt.Errorf("未找到 %s，请先安装FFmpeg (%s)，或在配置文件中通过 ffmpeg_path 指定ffmpeg路径", bin, ffmpegInstallHint()))
+		}
+	}
+	return nil
+}
+
+func ffmpegInstallHint() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "brew install ffmpeg"
+	case "windows":
+		return "从 https://ffmpeg.org/download.html 下载并加入PATH"
+	default:
+		return "sudo apt-get install ffmpeg"
+	}
+}
+
+// processes 判断是否要求对音频做截取、去除静音等处理，这些处理只能通过 ffmpeg 提取完成
+func (o ExtractOptions) processes() bool {
+	return o.TrimSilence || o.Normalize || o.Start > 0 || o.End > 0 || o.AudioTrack >= 0 ||
+		o.SampleRate > 0 || o.Channels > 0 || len(o.ExtraArgs) > 0
+}
+
+// useAudioDirectly 判断 generate 的输入能否跳过提取直接转录：输入已是转录接口支持的音频，
+// 且不需要额外处理时，重新编码只会浪费时间并降低音质。超过大小限制的无损音频仍然转码，
+// 压缩后通常不必切分
+func useAudioDirectly(path string, opts ExtractOptions) bool {
+	if !transcribableExtensions[fileExt(path)] || opts.processes() {
+		return false
+	}
+	if ext := fileExt(path); ext == ".wav" || ext == ".flac" {
+		if info, err := os.Stat(path); err == nil && info.Size() > maxAudioFileSize {
+			infof("无损音频超过25MB，转码为 %s 以减小体积", strings.TrimPrefix(opts.audioExt(), "."))
+			return false
+		}
+	}
+	return true
+}
+
+func probeDuration(ctx context.Context, config *Config, mediaPath string) (time.Duration, error) {
+	cmd := exec.CommandContext(ctx, config.ffprobeBinary(), "-v", "error", "-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1", mediaPath)
+	debugCommand(cmd)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, ffmpegError("ffprobe", mediaPath, err, nil)
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析媒体时长失败: %w", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// minSegmentTime 为按大小缩短分段时长的下限，更短仍超过25MB时放弃切分
+const minSegmentTime = 30 * time.Second
+
+// splitAudio 按固定时长将音频切分为多个分段，每段末尾额外保留 segmentOverlap 的重叠，返回实际使用的分段时长。
+// 分段直接复制原音频的编码，码率较高 (如 WAV) 时按平均码率缩短分段时长，留出余量保证每段不超过25MB；
+// 码率不均匀导致仍有分段过大时，将分段时长减半后重新切分
+func splitAudio(ctx context.Context, config *Config, audioPath, outputDir string, segmentTime time.Duration) ([]string, time.Duration, error) {
+	if segmentTime <= 0 {
+		return nil, 0, fmt.Errorf("分段时长必须大于0")
+	}
+
+	duration, err := probeDuration(ctx, config, audioPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := os.Stat(audioPath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("打开音频文件失败: %w", err)
+	}
+	if duration > 0 {
+		// 按平均码率估算25MB的九成能容纳的时长，再减去重叠部分
+		fit := time.Duration(float64(duration)*float64(maxAudioFileSize)*0.9/float64(info.Size())) - segmentOverlap
+		if fit = max(fit.Truncate(time.Second), minSegmentTime); fit < segmentTime {
+			infof("音频码率较高，分段时长由 %v 缩短为 %v，以保证每段不超过25MB", segmentTime, fit)
+			segmentTime = fit
+		}
+	}
+
+	for {
+		segments, err := cutSegments(ctx, config, audioPath, outputDir, duration, segmentTime)
+		if err != nil {
+			return nil, 0, err
+		}
+		oversized, err := oversizedSegment(segments)
+		if err != nil {
+			return nil, 0, err
+		}
+		if oversized == "" {
+			return segments, segmentTime, nil
+		}
+		if segmentTime/2 < minSegmentTime {
+			return nil, 0, fmt.Errorf("分段 %s 超过25MB，音频码率过高，请先转码为 mp3 等压缩格式", filepath.Base(oversized))
+		}
+		segmentTime /= 2
+		infof("分段 %s 超过25MB，分段时长缩短为 %v 后重新切分", filepath.Base(oversized), segmentTime)
+	}
+}
+
+// cutSegments 从头按 segmentTime 将音频切分到 outputDir，不重新编码
+func cutSegments(ctx context.Context, config *Config, audioPath, outputDir string, duration, segmentTime time.Duration) ([]string, error) {
+	ext := filepath.Ext(audioPath)
+	var segments []string
+	for start := time.Duration(0); start < duration; start += segmentTime {
+		segmentPath := filepath.Join(outputDir, fmt.Sprintf("segment-%03d%s", len(segments), ext))
+		cmd := exec.CommandContext(ctx, config.ffmpegBinary(), "-y",
+			"-ss", formatSeconds(start),
+			"-t", formatSeconds(segmentTime+segmentOverlap),
+			"-i", audioPath, "-c", "copy", segmentPath)
+		debugCommand(cmd)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("切分音频失败: %w", ffmpegError("ffmpeg", audioPath, err, output))
+		}
+		segments = append(segments, segmentPath)
+	}
+	return segments, nil
+}
+
+// oversizedSegment 返回第一个超过25MB的分段，都没有超过时返回空字符串
+func oversizedSegment(segments []string) (string, error) {
+	for _, segment := range segments {
+		info, err := os.Stat(segment)
+		if err != nil {
+			return "", fmt.Errorf("读取音频分段失败: %w", err)
+		}
+		if info.Size() > maxAudioFileSize {
+			return segment, nil
+		}
+	}
+	return "", nil
+}
+
+// DefaultLoudness 为 -normalize 默认的目标响度 (LUFS)，与常见播客和在线视频平台一致
+const DefaultLoudness = -16
+
+// loudnormFilter 返回将响度统一到 target LUFS 的 loudnorm 滤镜，真峰值限制在 -1.5 dBTP
+func loudnormFilter(target float64) string {
+	return fmt.Sprintf("loudnorm=I=%s:TP=-1.5:LRA=11", strconv.FormatFloat(target, 'f', -1, 64))
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// mergeOverlap 将相邻分段的转录文本拼接起来，并去掉因分段重叠而重复出现的开头部分
+func mergeOverlap(prev, next string) string {
+	const (
+		minOverlap = 4
+		maxOverlap = 200
+	)
+
+	prev = strings.TrimSpace(prev)
+	next = strings.TrimSpace(next)
+	if prev == "" {
+		return next
+	}
+	if next == "" {
+		return prev
+	}
+
+	p, n := []rune(prev), []rune(next)
+	limit := min(maxOverlap, len(p), len(n))
+	for k := limit; k >= minOverlap; k-- {
+		if string(p[len(p)-k:]) == string(n[:k]) {
+			n = n[k:]
+			break
+		}
+	}
+
+	rest := strings.TrimSpace(string(n))
+	if rest == "" {
+		return prev
+	}
+	return prev + " " + rest
+}
+
+// audioCodec 为提取音频时可选的编码
+type audioCodec struct {
+	encoder string
+	ext     string
+	// lossless 为 true 时码率设置无效
+	lossless bool
+}
+
+// 支持的音频编码，均为转录接口可以直接接收的格式
+var audioCodecs = map[string]audioCodec{
+	"mp3":  {encoder: "libmp3lame", ext: ".mp3"},
+	"wav":  {encoder: "pcm_s16le", ext: ".wav", lossless: true},
+	"flac": {encoder: "flac", ext: ".flac", lossless: true},
+	"opus": {encoder: "libopus", ext: ".ogg"},
+	"aac":  {encoder: "aac", ext: ".m4a"},
+}
+
+// warnLosslessSize 在无损编码提取的音频超过25MB时给出提示：splitAudio 会按码率缩短分段，
+// 转录仍能完成，但分段更多、上传更慢
+func warnLosslessSize(audioPath string) {
+	info, err := os.Stat(audioPath)
+	if err != nil || info.Size() <= maxAudioFileSize {
+		return
+	}
+	warnf("提取的无损音频为 %.1fMB，超过25MB，将切分为较短的分段转录；可用 -sample-rate 16000 -channels 1 或 -audio-codec mp3 减小体积",
+		float64(info.Size())/1024/1024)
+}
+
+// 默认使用 mp3 编码，兼容性最好
+const defaultAudioCodec = "mp3"
+
+func validateAudioCodec(name string) error {
+	if _, ok := audioCodecs[name]; !ok {
+		return fmt.Errorf("不支持的音频编码: %s (可选: mp3, wav, flac, opus, aac)", name)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package videonote
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// fakeSplitTools 写入模拟的 ffprobe 和 ffmpeg：ffprobe 报告 duration 秒，ffmpeg 按 -t 的时长
+// 以每秒 rate 字节写出分段，从头开始的分段按 firstRate 计算，模拟码率不均匀的音频
+func fakeSplitTools(t *testing.T, duration, rate, firstRate int) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	ffprobe := fmt.Sprintf("#!/bin/sh\necho %d\n", duration)
+	ffmpeg := fmt.Sprintf(`#!/bin/sh
+rate=%d
+while [ $# -gt 1 ]; do
+	case "$1" in
+	-ss) [ "$2" = "0.000" ] && rate=%d ;;
+	-t) t=$2 ;;
+	esac
+	shift
+done
+truncate -s "$(awk "BEGIN { printf \"%%d\", $t * $rate }")" "$1"
+`, rate, firstRate)
+	for name, script := range map[string]string{"ffprobe": ffprobe, "ffmpeg": ffmpeg} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Config{FFmpegPath: filepath.Join(dir, "ffmpeg")}
+}
+
+// 高码率的音频 (如 44.1kHz 立体声 WAV) 按时长切分后每段仍不能超过25MB
+func TestSplitAudioSize(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("模拟的 ffmpeg 为 shell 脚本")
+	}
+	const duration = 600
+	tests := []struct {
+		name      string
+		rate      int
+		firstRate int
+		// want 为期望的分段时长
+		want time.Duration
+	}{
+		// 60MB 的音频按平均码率缩短到 (600s * 25MB * 0.9 / 60MB - 2s) 取整
+		{"constant bitrate", 100_000, 100_000, 233 * time.Second},
+		// 第一段码率是平均的两倍，超过25MB后分段时长减半重新切分
+		{"loud start", 100_000, 200_000, 233 * time.Second / 2},
+		// 码率较低时保持 -segment-time
+		{"low bitrate", 16_000, 16_000, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := fakeSplitTools(t, duration, tt.rate, tt.firstRate)
+			input := filepath.Join(t.TempDir(), "lecture.wav")
+			f, err := os.Create(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Truncate(int64(duration * tt.rate)); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			segments, segmentTime, err := splitAudio(context.Background(), config, input, t.TempDir(), 10*time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if segmentTime != tt.want {
+				t.Errorf("分段时长为 %v，期望 %v", segmentTime, tt.want)
+			}
+			if want := int((duration*time.Second + segmentTime - 1) / segmentTime); len(segments) != want {
+				t.Errorf("切分为 %d 段，期望 %d 段", len(segments), want)
+			}
+			for _, segment := range segments {
+				info, err := os.Stat(segment)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.Size() > maxAudioFileSize {
+					t.Errorf("分段 %s 为 %d 字节，超过25MB", filepath.Base(segment), info.Size())
+				}
+			}
+		})
+	}
+}
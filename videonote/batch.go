@@ -0,0 +1,232 @@
+package videonote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 批量处理目录时识别为视频的文件扩展名
+var videoExtensions = map[string]bool{
+	".mp4":  true,
+	".mkv":  true,
+	".mov":  true,
+	".avi":  true,
+	".webm": true,
+	".flv":  true,
+	".wmv":  true,
+	".m4v":  true,
+	".ts":   true,
+}
+
+// ExpandInputs 将 -i 参数展开为待处理的文件列表；目录或通配符视为批量处理，
+// 通配符中的 ** 匹配任意层子目录
+func ExpandInputs(input string) ([]string, bool, error) {
+	if isURL(input) {
+		return []string{input}, false, nil
+	}
+
+	if strings.Contains(filepath.ToSlash(input), "**") {
+		files, err := globRecursive(input)
+		if err != nil {
+			return nil, false, err
+		}
+		return files, true, nil
+	}
+	if strings.ContainsAny(input, "*?[") {
+		matches, err := filepath.Glob(input)
+		if err != nil {
+			return nil, false, fmt.Errorf("无效的通配符 %q: %w", input, err)
+		}
+		var files []string
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() {
+				files = append(files, match)
+			}
+		}
+		if len(files) == 0 {
+			return nil, false, fmt.Errorf("没有文件匹配 %q", input)
+		}
+		return files, true, nil
+	}
+
+	info, err := os.Stat(input)
+	if err != nil || !info.IsDir() {
+		return []string{input}, false, nil
+	}
+
+	// 递归查找子目录中的视频，配合 -output-dir 可以在输出目录中还原目录结构
+	var files []string
+	err = filepath.WalkDir(input, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && videoExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("读取目录失败: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, false, fmt.Errorf("目录 %s 中没有视频文件", input)
+	}
+	sort.Strings(files)
+	return files, true, nil
+}
+
+// InputRoot 返回 -i 参数对应的输入根目录：目录为其本身，通配符为第一个通配符之前的目录，文件为其所在目录
+func InputRoot(source string) string {
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		return source
+	}
+	if !strings.ContainsAny(source, "*?[") {
+		return filepath.Dir(source)
+	}
+	var parts []string
+	for _, part := range strings.Split(filepath.ToSlash(source), "/") {
+		if strings.ContainsAny(part, "*?[") {
+			break
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "."
+	}
+	return filepath.FromSlash(strings.Join(parts, "/"))
+}
+
+// PlanOutputs 计算每个输入的笔记路径。dir 为空时返回空路径，使用默认位置；
+// roots 不为空时按输入在根目录下的相对路径在 dir 中还原目录结构，否则直接放在 dir 中。
+// 多个输入得到相同路径时依次加上 -2、-3 等后缀，避免相互覆盖
+func PlanOutputs(inputs, roots []string, dir, ext string) []string {
+	outputs := make([]string, len(inputs))
+	if dir == "" {
+		return outputs
+	}
+
+	used := make(map[string]bool)
+	for i, input := range inputs {
+		rel := filepath.Base(input)
+		if roots != nil && !isURL(input) {
+			if r, err := filepath.Rel(roots[i], input); err == nil && !strings.HasPrefix(r, "..") {
+				rel = r
+			}
+		}
+		base := filepath.Join(dir, strings.TrimSuffix(rel, filepath.Ext(rel)))
+		output := base + ext
+		for n := 2; used[output]; n++ {
+			output = fmt.Sprintf("%s-%d%s", base, n, ext)
+		}
+		used[output] = true
+		outputs[i] = output
+	}
+	return outputs
+}
+
+// RunBatch 以最多 jobs 个并发处理多个视频，outputs 为各自的笔记路径 (为空时使用默认位置)；
+// 单个文件失败不影响其余文件，结束后汇总所有错误；opts.FailFast 时第一个失败后不再启动新的文件
+func RunBatch(ctx context.Context, config *Config, inputs, outputs []string, jobs int, opts GenerateOptions, state *BatchState) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	errs := make([]error, len(inputs))
+	var stopped atomic.Bool
+	skipped := 0
+	var mu sync.Mutex
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				// 已取消时不再启动新的任务
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				// 已开始的文件继续完成，不留下不完整的笔记
+				if stopped.Load() {
+					mu.Lock()
+					skipped++
+					mu.Unlock()
+					continue
+				}
+
+				// 未指定输出目录时按 Generate 的默认位置判断笔记是否还在；
+				// 在线视频的文件名要下载后才知道，使用状态文件中记录的路径
+				input, output := inputs[i], outputs[i]
+				if output == "" && !isURL(input) {
+					output = defaultNotePath(input, opts.Format)
+				}
+				if state != nil && state.Done(input, output) {
+					infof("[%d/%d] 上次运行已完成，跳过: %s", i+1, len(inputs), input)
+					continue
+				}
+				infof("[%d/%d] 开始处理: %s", i+1, len(inputs), input)
+				written, err := Generate(ctx, config, input, outputs[i], opts)
+				metrics.fileDone(err)
+				if err != nil {
+					errorf("[%d/%d] 处理失败: %s: %v", i+1, len(inputs), input, err)
+					errs[i] = err
+					if opts.FailFast && stopped.CompareAndSwap(false, true) {
+						infof("-fail-fast: 不再处理其余文件")
+					}
+					continue
+				}
+				if state != nil {
+					// 只写入转录或字幕时没有笔记路径
+					if written == "" {
+						written = output
+					}
+					if err := state.Complete(input, written); err != nil {
+						warnf("写入状态文件失败: %v", err)
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range inputs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var failed []error
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", inputs[i], err))
+		}
+	}
+
+	if skipped > 0 {
+		infof("批量处理结束: 成功 %d 个，失败 %d 个，未处理 %d 个", len(inputs)-len(failed)-skipped, len(failed), skipped)
+	} else {
+		infof("批量处理完成: 成功 %d 个，失败 %d 个", len(inputs)-len(failed), len(failed))
+	}
+	if !opts.Quiet && logLevel.Level() <= slog.LevelInfo {
+		metrics.report().Print(os.Stderr)
+	}
+	switch {
+	case len(failed) == 0:
+		return nil
+	case opts.FailFast:
+		return failed[0]
+	case len(failed) == len(inputs):
+		// 汇总的错误仍可用 errors.Is 判断类别，全部失败时多半是同一个原因 (如密钥无效)
+		return fmt.Errorf("%d 个文件全部处理失败:\n%w", len(failed), errors.Join(failed...))
+	}
+	return markError(ErrPartial, fmt.Errorf("%d 个文件处理失败:\n%w", len(failed), errors.Join(failed...)))
+}
@@ -0,0 +1,68 @@
+package videonote
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSRT = `1
+00:00:00,000 --> 00:00:05,000
+今天介绍批量处理的断点续传。
+`
+
+// 未指定 -o 时笔记写在输入旁边，再次运行应按状态文件跳过已完成的文件
+func TestRunBatchResumeWithoutOutputDir(t *testing.T) {
+	api := newFakeAPI(t)
+	config := api.config(t)
+	dir := t.TempDir()
+	var inputs []string
+	for _, name := range []string{"a.srt", "b.srt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(testSRT), 0644); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, path)
+	}
+	opts := testGenerateOptions()
+	// 覆盖已有的笔记，只有状态文件能让第二次运行跳过
+	opts.Overwrite = OverwriteReplace
+	outputs := PlanOutputs(inputs, nil, "", opts.Format.Ext())
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	run := func() {
+		t.Helper()
+		state, err := LoadBatchState(statePath, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := RunBatch(context.Background(), config, inputs, outputs, 2, opts, state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run()
+	if n := api.Calls(completionsPath); n != len(inputs) {
+		t.Fatalf("第一次运行收到 %d 个摘要请求，期望 %d 个", n, len(inputs))
+	}
+	for _, input := range inputs {
+		if _, err := os.Stat(defaultNotePath(input, opts.Format)); err != nil {
+			t.Fatalf("没有写入默认位置的笔记: %v", err)
+		}
+	}
+
+	run()
+	if n := api.Calls(completionsPath); n != len(inputs) {
+		t.Errorf("再次运行没有跳过已完成的文件，共收到 %d 个摘要请求", n)
+	}
+
+	// 删除笔记后应重新生成
+	if err := os.Remove(defaultNotePath(inputs[0], opts.Format)); err != nil {
+		t.Fatal(err)
+	}
+	run()
+	if n := api.Calls(completionsPath); n != len(inputs)+1 {
+		t.Errorf("笔记被删除后收到 %d 个摘要请求，期望 %d 个", n, len(inputs)+1)
+	}
+}
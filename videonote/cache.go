@@ -0,0 +1,111 @@
+package videonote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// transcriptCache 按音频内容哈希缓存转录结果，dir 为空时不缓存
+type transcriptCache struct {
+	dir string
+}
+
+func newTranscriptCache(dir string) *transcriptCache {
+	return &transcriptCache{dir: dir}
+}
+
+// DefaultCacheDir 返回默认的缓存目录，如 ~/.cache/video-note
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "video-note")
+}
+
+// key 根据音频内容和影响转录结果的参数计算缓存键，更换模型后缓存自动失效
+func (c *transcriptCache) key(config *Config, audioPath string, opts TranscribeOptions) (string, error) {
+	if c.dir == "" {
+		return "", nil
+	}
+
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return "", fmt.Errorf("打开音频文件失败: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("读取音频文件失败: %w", err)
+	}
+	// 本地转录以模型文件区分，与 Whisper 接口的缓存互不混用
+	model := config.TranscribeModel
+	if opts.Backend == transcriberLocal {
+		model = "local:" + config.WhisperModel
+	}
+	fmt.Fprintf(h, "\x00model=%s\x00translate=%t\x00timestamps=%t\x00segment=%s\x00language=%s\x00prompt=%s",
+		model, opts.Translate, opts.Timestamps, opts.SegmentTime, opts.Language, opts.Prompt)
+	// 只在指定时加入键中，避免已有的缓存失效
+	if opts.ResponseFormat != "" || opts.Temperature != 0 {
+		fmt.Fprintf(h, "\x00format=%s\x00temperature=%g", opts.ResponseFormat, opts.Temperature)
+	}
+	// 检查置信度时需要 verbose_json 的结果，不能复用只请求 text 时缓存的转录
+	if opts.ConfidenceThreshold < 0 && opts.Backend != transcriberLocal {
+		fmt.Fprintf(h, "\x00response=%s\x00confidence=%g", opts.responseFormat(), opts.ConfidenceThreshold)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *transcriptCache) path(key string) string {
+	return filepath.Join(c.dir, "transcripts", key+".json")
+}
+
+// Load 读取缓存的转录结果，缓存不存在或已损坏时返回 false
+func (c *transcriptCache) Load(key string) (*Transcript, bool) {
+	if c.dir == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var transcript Transcript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		return nil, false
+	}
+	return &transcript, true
+}
+
+// Store 保存转录结果，先写临时文件再重命名，避免并发运行时读到不完整的缓存
+func (c *transcriptCache) Store(key string, transcript *Transcript) error {
+	if c.dir == "" {
+		return nil
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(transcript)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
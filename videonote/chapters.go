@@ -0,0 +1,63 @@
+package videonote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Chapter 为视频中嵌入的章节标记
+type Chapter struct {
+	Title string
+	Start time.Duration
+	End   time.Duration
+}
+
+// probeChapters 用 ffprobe 读取视频中的章节，没有章节时返回空列表
+func probeChapters(ctx context.Context, config *Config, mediaPath string) ([]Chapter, error) {
+	cmd := exec.CommandContext(ctx, config.ffprobeBinary(), "-v", "error", "-show_chapters", "-of", "json", mediaPath)
+	debugCommand(cmd)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, ffmpegError("ffprobe", mediaPath, err, nil)
+	}
+	return parseChapters(output)
+}
+
+func parseChapters(data []byte) ([]Chapter, error) {
+	var result struct {
+		Chapters []struct {
+			StartTime string            `json:"start_time"`
+			EndTime   string            `json:"end_time"`
+			Tags      map[string]string `json:"tags"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析章节信息失败: %w", err)
+	}
+
+	chapters := make([]Chapter, 0, len(result.Chapters))
+	for i, c := range result.Chapters {
+		start, err := strconv.ParseFloat(c.StartTime, 64)
+		if err != nil {
+			return nil, fmt.Errorf("解析章节时间失败: %w", err)
+		}
+		end, err := strconv.ParseFloat(c.EndTime, 64)
+		if err != nil {
+			return nil, fmt.Errorf("解析章节时间失败: %w", err)
+		}
+		title := c.Tags["title"]
+		if title == "" {
+			title = fmt.Sprintf("第 %d 章", i+1)
+		}
+		chapters = append(chapters, Chapter{
+			Title: title,
+			Start: secondsToDuration(start),
+			End:   secondsToDuration(end),
+		})
+	}
+	return chapters, nil
+}
@@ -0,0 +1,111 @@
+package videonote
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 相邻片段之间的停顿超过该时长时另起一段
+const paragraphPause = 2 * time.Second
+
+var (
+	// 英文中的语气词，如 um、uh、erm，连同其后的逗号一起去除
+	englishFillers = regexp.MustCompile(`(?i)\b(?:u+m+|u+h+|e+r+m+|h+m+)\b[,，]?\s*`)
+	// 中文中的语气词，如 嗯、呃，连同其后的标点一起去除
+	chineseFillers = regexp.MustCompile(`[嗯呃]+[,，、。…]*`)
+	// 标点前多余的空格
+	spaceBeforePunct = regexp.MustCompile(`\s+([,.!?;:，。！？；：、])`)
+	// 连续重复的中文标点
+	repeatedPunct = regexp.MustCompile(`([，。！？；、])[，。！？；、]+`)
+)
+
+// cleanTranscript 清理转录文本：去除语气词和口吃式的重复词、规范空白和标点；
+// 有时间戳时清理每个片段，并在停顿较长处分段
+func cleanTranscript(t *Transcript) {
+	if len(t.Segments) == 0 {
+		t.Text = cleanText(t.Text)
+		return
+	}
+
+	segments := t.Segments[:0]
+	for _, seg := range t.Segments {
+		seg.Text = cleanText(seg.Text)
+		if seg.Text != "" {
+			segments = append(segments, seg)
+		}
+	}
+	t.Segments = segments
+	t.Text = joinParagraphs(segments)
+}
+
+// cleanText 清理一段文本，保留原有的换行
+func cleanText(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = englishFillers.ReplaceAllString(line, "")
+		line = chineseFillers.ReplaceAllString(line, "")
+		line = strings.Join(dedupeWords(strings.Fields(line)), " ")
+		line = removeCJKSpaces(line)
+		line = spaceBeforePunct.ReplaceAllString(line, "$1")
+		line = repeatedPunct.ReplaceAllString(line, "$1")
+		lines[i] = strings.TrimLeft(line, ",，、 ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// dedupeWords 去除连续重复的单词，如 "the the" 只保留一个
+func dedupeWords(words []string) []string {
+	var result []string
+	for _, word := range words {
+		if n := len(result); n > 0 && strings.EqualFold(result[n-1], word) && isLetters(strings.ToLower(word)) {
+			continue
+		}
+		result = append(result, word)
+	}
+	return result
+}
+
+// removeCJKSpaces 去除两个中日韩字符之间的空格，Whisper 常在中文片段间插入空格
+func removeCJKSpaces(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if r == ' ' && i > 0 && i < len(runes)-1 && isCJK(runes[i-1]) && isCJK(runes[i+1]) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isCJK 判断字符是否为中日韩文字或全角标点
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		r >= 0x3000 && r <= 0x303F || r >= 0xFF00 && r <= 0xFFEF
+}
+
+// joinParagraphs 拼接片段文本，停顿超过 paragraphPause 时空一行另起一段，
+// 说话人变化时另起一行
+func joinParagraphs(segments []Segment) string {
+	var b strings.Builder
+	for i, seg := range segments {
+		if i > 0 {
+			prev := segments[i-1]
+			switch {
+			case seg.Start-prev.End >= paragraphPause:
+				b.WriteString("\n\n")
+			case seg.Speaker != "" && seg.Speaker != prev.Speaker:
+				b.WriteString("\n")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		if seg.Speaker != "" && (i == 0 || seg.Speaker != segments[i-1].Speaker || seg.Start-segments[i-1].End >= paragraphPause) {
+			b.WriteString("[" + seg.Speaker + "] ")
+		}
+		b.WriteString(seg.Text)
+	}
+	return removeCJKSpaces(b.String())
+}
@@ -0,0 +1,150 @@
+package videonote
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sentenceID 匹配 numberedTranscript 中的句子编号和模拟回复中的编号
+var sentenceID = regexp.MustCompile(`第(\d+)句|S(\d+)`)
+
+// numberedTranscript 返回 n 句带编号的转录文本
+func numberedTranscript(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "这是第%d句，我们讨论了并发请求的处理方式和结果的顺序。", i)
+	}
+	return b.String()
+}
+
+// sentenceIDs 按出现顺序返回文本中的句子编号
+func sentenceIDs(text string) []int {
+	var ids []int
+	for _, m := range sentenceID.FindAllStringSubmatch(text, -1) {
+		id, _ := strconv.Atoi(m[1] + m[2])
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// echoSentences 作为模拟接口的回复，按顺序列出提示词中各句的编号，如 "要点 S1 S2 S3"
+func echoSentences(prompt string) string {
+	parts := []string{"要点"}
+	for _, id := range sentenceIDs(prompt) {
+		parts = append(parts, "S"+strconv.Itoa(id))
+	}
+	return strings.Join(parts, " ")
+}
+
+// summarizeNumbered 将 n 句带编号的转录分成多个小块并发摘要，返回写入的笔记
+func summarizeNumbered(t *testing.T, api *fakeAPI, n, concurrency int) string {
+	t.Helper()
+	config := api.config(t)
+	config.ChunkTokens = 200
+	api.Complete = echoSentences
+
+	output := filepath.Join(t.TempDir(), "notes.txt")
+	err := Summarize(context.Background(), config, strings.NewReader(numberedTranscript(n)), output, SummarizeOptions{
+		Ratio:       0.2,
+		Format:      FormatText,
+		Mode:        ModeFlat,
+		Concurrency: concurrency,
+		Quiet:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// 各块的摘要并发完成，笔记中应包含每一块的摘要且顺序与原文一致；用 go test -race 运行可检查数据竞争
+func TestSummarizeConcurrentChunks(t *testing.T) {
+	const sentences = 40
+	api := newFakeAPI(t)
+	// 按编号打乱各请求的完成时间
+	api.Delay = func(prompt string) time.Duration {
+		ids := sentenceIDs(prompt)
+		if len(ids) == 0 {
+			return 0
+		}
+		return time.Duration(ids[0]*7%5) * 5 * time.Millisecond
+	}
+
+	notes := summarizeNumbered(t, api, sentences, 4)
+	if n := api.Calls(completionsPath); n < 4 {
+		t.Fatalf("只收到 %d 个摘要请求，转录没有被分成足够多的块", n)
+	}
+	want := make([]int, sentences)
+	for i := range want {
+		want[i] = i + 1
+	}
+	if got := sentenceIDs(notes); !slices.Equal(got, want) {
+		t.Errorf("笔记中的句子编号为 %v，期望 1-%d 依次出现\n%s", got, sentences, notes)
+	}
+}
+
+// 前面的块最后返回时，各部分仍应按原文顺序排列
+func TestSummarizeOutOfOrderResponses(t *testing.T) {
+	const sentences = 30
+	api := newFakeAPI(t)
+	// 越靠前的块等待越久，所有块同时请求时按相反的顺序返回
+	api.Delay = func(prompt string) time.Duration {
+		ids := sentenceIDs(prompt)
+		if len(ids) == 0 {
+			return 0
+		}
+		return time.Duration(sentences-ids[0]) * 3 * time.Millisecond
+	}
+
+	notes := summarizeNumbered(t, api, sentences, 16)
+	var firsts []int
+	for _, prompt := range api.Finished() {
+		firsts = append(firsts, sentenceIDs(prompt)[0])
+	}
+	if len(firsts) < 3 || slices.IsSorted(firsts) {
+		t.Fatalf("摘要没有乱序返回，各块首句编号依次为 %v", firsts)
+	}
+
+	sections := strings.Split(notes, "部分结束")
+	if len(sections) != len(firsts) {
+		t.Fatalf("笔记有 %d 个部分，期望 %d 个\n%s", len(sections), len(firsts), notes)
+	}
+	slices.Sort(firsts)
+	for i, section := range sections {
+		if ids := sentenceIDs(section); len(ids) == 0 || ids[0] != firsts[i] {
+			t.Errorf("第 %d 部分应从第 %d 句开始: %q", i+1, firsts[i], section)
+		}
+	}
+}
+
+// 同时进行中的摘要请求不应超过 Concurrency
+func TestSummarizeConcurrencyLimit(t *testing.T) {
+	for _, limit := range []int{1, 3} {
+		t.Run(strconv.Itoa(limit), func(t *testing.T) {
+			api := newFakeAPI(t)
+			api.Delay = func(string) time.Duration { return 10 * time.Millisecond }
+
+			summarizeNumbered(t, api, 40, limit)
+			if n := api.Calls(completionsPath); n <= limit {
+				t.Fatalf("只收到 %d 个摘要请求，无法检查并发上限 %d", n, limit)
+			}
+			if got := api.MaxInflight(); got > limit {
+				t.Errorf("同时进行中的请求最多为 %d 个，超过了上限 %d", got, limit)
+			} else if got < limit {
+				t.Errorf("同时进行中的请求最多为 %d 个，没有用满上限 %d", got, limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package videonote
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultConfidenceThreshold 为命令行 -confidence-threshold 的默认值，默认不检查：检查置信度需要 verbose_json，
+// gpt-4o-transcribe 系列不支持。SuggestedConfidenceThreshold 与 Whisper 判断解码失败的 logprob_threshold 一致
+const (
+	DefaultConfidenceThreshold   = 0.0
+	SuggestedConfidenceThreshold = -1.0
+)
+
+const (
+	// noSpeechThreshold 为 no_speech_prob 的上限，超过时该片段多半是静音或噪声，转录出的文字可能是模型臆造的
+	noSpeechThreshold = 0.6
+	// confidenceGap 为合并相邻低置信度片段时允许的间隔
+	confidenceGap = 2 * time.Second
+	// maxConfidenceRanges 为警告中最多列出的时间段数量
+	maxConfidenceRanges = 10
+)
+
+// SegmentConfidence 为 Whisper 在 verbose_json 中返回的片段置信度
+type SegmentConfidence struct {
+	Start        time.Duration
+	End          time.Duration
+	AvgLogprob   float64
+	NoSpeechProb float64
+}
+
+// lowConfidenceRanges 返回置信度低于 threshold 或可能没有语音的时间段，相邻的合并为一段；
+// tm 用于换算回原视频的时间
+func lowConfidenceRanges(confidence []SegmentConfidence, threshold float64, tm *timeMap) []Segment {
+	var ranges []Segment
+	for _, c := range confidence {
+		if c.AvgLogprob >= threshold && c.NoSpeechProb <= noSpeechThreshold {
+			continue
+		}
+		start, end := tm.original(c.Start), tm.original(c.End)
+		if n := len(ranges); n > 0 && start-ranges[n-1].End <= confidenceGap {
+			ranges[n-1].End = max(ranges[n-1].End, end)
+			continue
+		}
+		ranges = append(ranges, Segment{Start: start, End: end})
+	}
+	return ranges
+}
+
+// warnLowConfidence 列出置信度低的时间段，提醒笔记中对应的内容可能不准确；threshold 为 0 时不检查
+func warnLowConfidence(t *Transcript, threshold float64, tm *timeMap) {
+	if threshold >= 0 {
+		return
+	}
+	ranges := lowConfidenceRanges(t.Confidence, threshold, tm)
+	if len(ranges) == 0 {
+		return
+	}
+	list := make([]string, 0, maxConfidenceRanges)
+	for _, r := range ranges[:min(len(ranges), maxConfidenceRanges)] {
+		list = append(list, formatTimestamp(r.Start)+"-"+formatTimestamp(r.End))
+	}
+	more := ""
+	if len(ranges) > maxConfidenceRanges {
+		more = fmt.Sprintf(" 等%d处", len(ranges))
+	}
+	warnf("以下时间段的转录置信度较低，笔记中对应的内容可能不准确: %s%s", strings.Join(list, ", "), more)
+}
@@ -0,0 +1,372 @@
+package videonote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/sashabaranov/go-openai"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultTranscribeModel = openai.Whisper1
+	defaultSummarizeModel  = openai.GPT3Dot5Turbo
+)
+
+type Config struct {
+	OpenAIAPIKey string `json:"openai_api_key"`
+	// OrgID 为 OpenAI 组织ID，用于多组织账号的计费归属
+	OrgID string `json:"org_id"`
+	// Headers 为附加到所有接口请求的 HTTP 头，如企业代理要求的认证头
+	Headers map[string]string `json:"headers"`
+	// Model 为旧版配置项，未单独配置 TranscribeModel/SummarizeModel 时用于推断二者
+	Model string `json:"model"`
+	// TranscribeModel 为语音转录模型，如 whisper-1
+	TranscribeModel string `json:"transcribe_model"`
+	// SummarizeModel 为生成摘要使用的对话模型，如 gpt-3.5-turbo
+	SummarizeModel string `json:"summarize_model"`
+	// FallbackModels 为 SummarizeModel 限流或服务不可用时依次尝试的备用模型
+	FallbackModels []string `json:"fallback_models"`
+	// SystemPrompt 为摘要请求的系统消息，设定助手的角色和输出规范；为空时按提示词语言使用内置的系统消息，
+	// 为 none 时不发送系统消息 (部分模型不支持 system 角色)
+	SystemPrompt string `json:"system_prompt"`
+	// BaseURL 为OpenAI兼容接口的地址，为空时使用官方接口
+	BaseURL string `json:"base_url"`
+	// AzureEndpoint 为 Azure OpenAI 资源地址，设置后通过 Azure 调用转录和摘要接口
+	AzureEndpoint string `json:"azure_endpoint"`
+	// AzureAPIVersion 为 Azure OpenAI 的 api-version 参数，为空时使用客户端默认值
+	AzureAPIVersion string `json:"azure_api_version"`
+	// AzureTranscribeDeployment 为转录模型在 Azure 上的部署名称
+	AzureTranscribeDeployment string `json:"azure_transcribe_deployment"`
+	// AzureSummarizeDeployment 为摘要模型在 Azure 上的部署名称
+	AzureSummarizeDeployment string `json:"azure_summarize_deployment"`
+	// MaxAttempts 为单次API调用遇到限流或服务端错误时的最大尝试次数
+	MaxAttempts int `json:"max_attempts"`
+	// RequestsPerMinute 限制生成摘要时每分钟发起的请求数，0 表示不限制
+	RequestsPerMinute int `json:"requests_per_minute"`
+	// FFmpegPath 为 ffmpeg 可执行文件路径，为空时从 PATH 中查找；ffprobe 需位于同一目录
+	FFmpegPath string `json:"ffmpeg_path"`
+	// Pricing 覆盖或补充内置的模型价格表，用于 -dry-run 估算费用
+	Pricing map[string]ModelPrice `json:"pricing"`
+	// ChunkTokens 为生成摘要时每个文本块的 token 上限
+	ChunkTokens int `json:"chunk_tokens"`
+	// DiarizeCommand 为说话人分离命令及其参数，音频路径作为最后一个参数传入，需输出 RTTM
+	DiarizeCommand []string `json:"diarize_command"`
+	// Temperature、TopP 和 MaxTokens 为生成摘要时的采样参数，可被命令行参数覆盖
+	Temperature *float32 `json:"temperature"`
+	TopP        *float32 `json:"top_p"`
+	// MaxTokens 为每次摘要请求的输出token上限，为 0 时按摘要比例自动计算
+	MaxTokens int `json:"max_tokens"`
+	// 提取音频的编码、采样率、声道数和码率，可被命令行参数覆盖
+	AudioCodec      string `json:"audio_codec"`
+	AudioSampleRate int    `json:"audio_sample_rate"`
+	AudioChannels   int    `json:"audio_channels"`
+	AudioBitrate    string `json:"audio_bitrate"`
+	// Loudness 为 -normalize 的目标响度 (LUFS)，为 0 时使用 DefaultLoudness
+	Loudness float64 `json:"loudness"`
+	// Transcriber 为转录后端，openai 或 local (本地 whisper.cpp)，可被 -transcriber 覆盖
+	Transcriber string `json:"transcriber"`
+	// WhisperBinary 为 whisper.cpp 可执行文件路径，为空时从 PATH 中查找 whisper-cli
+	WhisperBinary string `json:"whisper_binary"`
+	// WhisperModel 为 whisper.cpp 使用的 ggml 模型文件路径，如 models/ggml-base.bin
+	WhisperModel string `json:"whisper_model"`
+	// PostHook 为笔记写入后执行的命令及其参数，笔记路径作为最后一个参数传入，可被 -post-hook 覆盖
+	PostHook []string `json:"post_hook"`
+	// WebhookSecret 不为空时用于计算 -webhook 请求体的 HMAC-SHA256 签名，也可通过环境变量
+	// VIDEO_NOTE_WEBHOOK_SECRET 设置
+	WebhookSecret string `json:"webhook_secret"`
+	// Summarizer 为摘要后端，目前支持 openai (包括 Azure 和 base_url 指向的兼容接口)
+	Summarizer string `json:"summarizer"`
+	// Profiles 为命名的摘要参数组合，Stages 为流水线各阶段 (map、reduce、dedup、refine、extract) 使用的 profile 名称
+	Profiles map[string]Profile `json:"profiles"`
+	Stages   map[string]string  `json:"stages"`
+}
+
+// 配置文件支持的扩展名，同一位置有多个时按此顺序优先使用
+var configExtensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// ConfigSearchPaths 返回未指定 -config 时依次查找的配置文件路径；
+// 每个位置还会查找同名的 .yaml、.yml 和 .toml 文件
+func ConfigSearchPaths() []string {
+	var paths []string
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		paths = append(paths, filepath.Join(dir, "video-note", "config.json"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config", "video-note", "config.json"))
+	}
+	return append(paths, "config.json")
+}
+
+// findConfig 返回第一个存在的配置文件路径，都不存在时返回空字符串
+func findConfig() string {
+	for _, path := range ConfigSearchPaths() {
+		base := strings.TrimSuffix(path, filepath.Ext(path))
+		for _, ext := range configExtensions {
+			if info, err := os.Stat(base + ext); err == nil && !info.IsDir() {
+				return base + ext
+			}
+		}
+	}
+	return ""
+}
+
+func loadConfig(path string, config *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("打开配置文件失败: %w", err)
+	}
+	defer file.Close()
+
+	bytes, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	// YAML 和 TOML 先解码为通用结构再转为 JSON，与 JSON 配置共用字段名和解析规则
+	var raw map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(bytes, &raw)
+	case ".toml":
+		err = toml.Unmarshal(bytes, &raw)
+	default:
+		if err := json.Unmarshal(bytes, config); err != nil {
+			return fmt.Errorf("解析配置文件失败: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	if bytes, err = json.Marshal(raw); err != nil {
+		return fmt.Errorf("转换配置文件失败: %w", err)
+	}
+	if err := json.Unmarshal(bytes, config); err != nil {
+		return fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	return nil
+}
+
+// newOpenAIClient 根据配置创建 OpenAI 客户端，配置了 Azure 时按部署名称路由请求
+func newOpenAIClient(config *Config) *openai.Client {
+	var clientConfig openai.ClientConfig
+	if config.AzureEndpoint != "" {
+		clientConfig = config.azureClientConfig()
+	} else {
+		clientConfig = openai.DefaultConfig(config.OpenAIAPIKey)
+		if config.BaseURL != "" {
+			clientConfig.BaseURL = config.BaseURL
+		}
+		clientConfig.OrgID = config.OrgID
+	}
+	if len(config.Headers) > 0 {
+		clientConfig.HTTPClient = &headerClient{client: &http.Client{}, headers: config.Headers}
+	}
+	return openai.NewClientWithConfig(clientConfig)
+}
+
+// headerClient 在每个请求上附加配置的 HTTP 头
+type headerClient struct {
+	client  *http.Client
+	headers map[string]string
+}
+
+func (c *headerClient) Do(req *http.Request) (*http.Response, error) {
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+	return c.client.Do(req)
+}
+
+// validateHeaders 检查头名称是否为合法的 HTTP token，值中不能含有换行
+func validateHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if name == "" || strings.IndexFunc(name, func(r rune) bool { return !isTokenChar(r) }) >= 0 {
+			return fmt.Errorf("无效的HTTP头名称: %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return fmt.Errorf("HTTP头 %s 的值不能包含换行或空字符", name)
+		}
+	}
+	return nil
+}
+
+// isTokenChar 判断字符是否可以出现在 HTTP 头名称中 (RFC 7230 tchar)
+func isTokenChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	default:
+		return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+	}
+}
+
+func (c *Config) azureClientConfig() openai.ClientConfig {
+	clientConfig := openai.DefaultAzureConfig(c.OpenAIAPIKey, c.AzureEndpoint)
+	if c.AzureAPIVersion != "" {
+		clientConfig.APIVersion = c.AzureAPIVersion
+	}
+
+	defaultMapper := clientConfig.AzureModelMapperFunc
+	clientConfig.AzureModelMapperFunc = func(model string) string {
+		switch {
+		case model == c.TranscribeModel && c.AzureTranscribeDeployment != "":
+			return c.AzureTranscribeDeployment
+		case model == c.SummarizeModel && c.AzureSummarizeDeployment != "":
+			return c.AzureSummarizeDeployment
+		default:
+			return defaultMapper(model)
+		}
+	}
+	return clientConfig
+}
+
+// applyEnv 使用环境变量覆盖配置文件中的对应项；OPENAI_MODEL 按模型类型归入转录或摘要模型
+func (c *Config) applyEnv() {
+	if v := os.Getenv("OPENAI_API_KEY"); v != "" {
+		c.OpenAIAPIKey = v
+	}
+	if v := os.Getenv("OPENAI_BASE_URL"); v != "" {
+		c.BaseURL = v
+	}
+	if v := os.Getenv("OPENAI_ORG_ID"); v != "" {
+		c.OrgID = v
+	}
+	if v := os.Getenv("VIDEO_NOTE_WEBHOOK_SECRET"); v != "" {
+		c.WebhookSecret = v
+	}
+	if v := os.Getenv("OPENAI_MODEL"); v != "" {
+		if isTranscriptionModel(v) {
+			c.TranscribeModel = v
+		} else {
+			c.SummarizeModel = v
+		}
+	}
+}
+
+// applyDefaults 补全未配置的模型；旧版的 model 字段按模型类型归入转录或摘要
+func (c *Config) applyDefaults() {
+	if c.Model != "" {
+		if isTranscriptionModel(c.Model) {
+			if c.TranscribeModel == "" {
+				c.TranscribeModel = c.Model
+			}
+		} else if c.SummarizeModel == "" {
+			c.SummarizeModel = c.Model
+		}
+	}
+
+	if c.TranscribeModel == "" {
+		c.TranscribeModel = defaultTranscribeModel
+	}
+	if c.SummarizeModel == "" {
+		c.SummarizeModel = defaultSummarizeModel
+	}
+	if c.AudioCodec == "" {
+		c.AudioCodec = defaultAudioCodec
+	}
+	if c.Loudness == 0 {
+		c.Loudness = DefaultLoudness
+	}
+	if c.Transcriber == "" {
+		c.Transcriber = transcriberOpenAI
+	}
+	if c.Summarizer == "" {
+		c.Summarizer = summarizerOpenAI
+	}
+	if c.Temperature == nil {
+		temperature := float32(defaultTemperature)
+		c.Temperature = &temperature
+	}
+}
+
+func (c *Config) validate() error {
+	if c.AzureEndpoint == "" && (c.AzureAPIVersion != "" || c.AzureTranscribeDeployment != "" || c.AzureSummarizeDeployment != "") {
+		return fmt.Errorf("已设置 Azure 相关配置，但缺少 azure_endpoint")
+	}
+	if c.AzureEndpoint != "" && c.BaseURL != "" {
+		return fmt.Errorf("azure_endpoint 与 base_url 不能同时设置")
+	}
+	if c.AzureEndpoint != "" && c.OrgID != "" {
+		return fmt.Errorf("Azure OpenAI 不支持 org_id")
+	}
+	if err := validateHeaders(c.Headers); err != nil {
+		return fmt.Errorf("headers 配置无效: %w", err)
+	}
+
+	// 自建的兼容接口模型命名不统一，只对官方接口做检查
+	if c.BaseURL == "" && !isTranscriptionModel(c.TranscribeModel) {
+		return fmt.Errorf("transcribe_model %q 不是语音转录模型，请使用 %s 等转录模型", c.TranscribeModel, openai.Whisper1)
+	}
+	if c.BaseURL == "" && isTranscriptionModel(c.SummarizeModel) {
+		return fmt.Errorf("summarize_model %q 是语音转录模型，不能用于生成摘要", c.SummarizeModel)
+	}
+	for _, model := range c.FallbackModels {
+		if c.BaseURL == "" && isTranscriptionModel(model) {
+			return fmt.Errorf("fallback_models 中的 %q 是语音转录模型，不能用于生成摘要", model)
+		}
+	}
+
+	if c.Temperature != nil {
+		if err := validateTemperature(*c.Temperature); err != nil {
+			return err
+		}
+	}
+	if c.TopP != nil {
+		if err := validateTopP(*c.TopP); err != nil {
+			return err
+		}
+	}
+	if c.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens 不能为负数")
+	}
+	if err := c.validateProfiles(); err != nil {
+		return err
+	}
+	if err := validateAudioCodec(c.AudioCodec); err != nil {
+		return fmt.Errorf("audio_codec 无效: %w", err)
+	}
+	if c.Transcriber != transcriberOpenAI && c.Transcriber != transcriberLocal {
+		return fmt.Errorf("不支持的转录后端 transcriber: %s (可选: openai, local)", c.Transcriber)
+	}
+	if c.Summarizer != summarizerOpenAI {
+		return fmt.Errorf("不支持的摘要后端 summarizer: %s (可选: openai)", c.Summarizer)
+	}
+	return nil
+}
+
+func isTranscriptionModel(model string) bool {
+	model = strings.ToLower(model)
+	return strings.Contains(model, "whisper") || strings.Contains(model, "transcribe")
+}
+
+// PrepareConfig 依次加载配置文件、环境变量和默认值，并检查配置是否可用；
+// path 为空时在默认位置查找配置文件，都不存在时完全通过环境变量配置
+func PrepareConfig(config *Config, path string, requireKey bool) error {
+	if path == "" {
+		path = findConfig()
+	}
+	if path != "" {
+		if err := loadConfig(path, config); err != nil {
+			return markError(ErrConfig, fmt.Errorf("加载配置文件失败: %w", err))
+		}
+	}
+
+	config.applyEnv()
+	config.applyDefaults()
+	if err := config.validate(); err != nil {
+		return markError(ErrConfig, fmt.Errorf("配置无效: %w", err))
+	}
+
+	if requireKey && config.OpenAIAPIKey == "" {
+		return markError(ErrConfig, fmt.Errorf("OpenAI API Key 不能为空，请在配置文件中设置 openai_api_key 或设置环境变量 OPENAI_API_KEY"))
+	}
+	return nil
+}
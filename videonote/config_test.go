@@ -0,0 +1,123 @@
+package videonote
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrepareConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		// file 为写入配置目录的文件名和内容，name 为空时没有配置文件
+		file    [2]string
+		env     map[string]string
+		args    []string
+		wantKey string
+		wantURL string
+		// wantModel 为摘要模型，wantTranscribe 为空时为默认的转录模型
+		wantModel      string
+		wantTranscribe string
+	}{
+		{
+			name:      "json file",
+			file:      [2]string{"config.json", `{"openai_api_key": "sk-json", "summarize_model": "gpt-4o"}`},
+			wantKey:   "sk-json",
+			wantModel: "gpt-4o",
+		},
+		{
+			name:      "yaml file",
+			file:      [2]string{"config.yaml", "openai_api_key: sk-yaml\nbase_url: https://yaml.example/v1\nsummarize_model: gpt-4o\n"},
+			wantKey:   "sk-yaml",
+			wantURL:   "https://yaml.example/v1",
+			wantModel: "gpt-4o",
+		},
+		{
+			name:           "toml file with legacy model",
+			file:           [2]string{"config.toml", "openai_api_key = \"sk-toml\"\nmodel = \"whisper-1\"\n"},
+			wantKey:        "sk-toml",
+			wantModel:      defaultSummarizeModel,
+			wantTranscribe: "whisper-1",
+		},
+		{
+			name:      "env only",
+			env:       map[string]string{"OPENAI_API_KEY": "sk-env", "OPENAI_BASE_URL": "https://env.example/v1", "OPENAI_MODEL": "gpt-4o"},
+			wantKey:   "sk-env",
+			wantURL:   "https://env.example/v1",
+			wantModel: "gpt-4o",
+		},
+		{
+			name:      "env overrides file",
+			file:      [2]string{"config.yaml", "openai_api_key: sk-yaml\nbase_url: https://yaml.example/v1\nsummarize_model: gpt-4o\n"},
+			env:       map[string]string{"OPENAI_API_KEY": "sk-env"},
+			wantKey:   "sk-env",
+			wantURL:   "https://yaml.example/v1",
+			wantModel: "gpt-4o",
+		},
+		{
+			name:           "env transcription model",
+			file:           [2]string{"config.json", `{"openai_api_key": "sk-json", "summarize_model": "gpt-4o"}`},
+			env:            map[string]string{"OPENAI_MODEL": "gpt-4o-transcribe"},
+			wantKey:        "sk-json",
+			wantModel:      "gpt-4o",
+			wantTranscribe: "gpt-4o-transcribe",
+		},
+		{
+			name:      "flag overrides env and file",
+			file:      [2]string{"config.toml", "openai_api_key = \"sk-toml\"\nsummarize_model = \"gpt-4o\"\n"},
+			env:       map[string]string{"OPENAI_MODEL": "gpt-4.1"},
+			args:      []string{"-models", "gpt-4o-mini"},
+			wantKey:   "sk-toml",
+			wantModel: "gpt-4o-mini",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 隔离用户的配置目录和环境变量，未指定 -config 时只会找到测试写入的文件
+			dir := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", dir)
+			t.Setenv("HOME", dir)
+			for _, key := range []string{"OPENAI_API_KEY", "OPENAI_BASE_URL", "OPENAI_ORG_ID", "OPENAI_MODEL", "VIDEO_NOTE_WEBHOOK_SECRET"} {
+				t.Setenv(key, tt.env[key])
+			}
+			if tt.file[0] != "" {
+				path := filepath.Join(dir, "video-note", tt.file[0])
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(tt.file[1]), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// 与命令行相同：先读取配置文件和环境变量，再以其为默认值解析参数
+			config := &Config{}
+			if err := PrepareConfig(config, "", true); err != nil {
+				t.Fatal(err)
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			RegisterModelsFlag(fs, config)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			wantTranscribe := tt.wantTranscribe
+			if wantTranscribe == "" {
+				wantTranscribe = defaultTranscribeModel
+			}
+			if config.OpenAIAPIKey != tt.wantKey {
+				t.Errorf("API Key 为 %q，期望 %q", config.OpenAIAPIKey, tt.wantKey)
+			}
+			if config.BaseURL != tt.wantURL {
+				t.Errorf("base_url 为 %q，期望 %q", config.BaseURL, tt.wantURL)
+			}
+			if config.SummarizeModel != tt.wantModel {
+				t.Errorf("摘要模型为 %q，期望 %q", config.SummarizeModel, tt.wantModel)
+			}
+			if config.TranscribeModel != wantTranscribe {
+				t.Errorf("转录模型为 %q，期望 %q", config.TranscribeModel, wantTranscribe)
+			}
+		})
+	}
+}
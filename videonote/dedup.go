@@ -0,0 +1,115 @@
+package videonote
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const dedupPrompt = `以下是同一个视频按时间顺序分段生成的笔记摘要，由于各部分独立生成，后面的部分常常重复前面已经讲过的要点。请去除重复：
+- 后面部分中与前面部分意思相同或相近的要点直接删除，必要时将其中新增的细节并入首次出现的要点
+- 不要新增内容，不要改写没有重复的要点，保持原有的格式
+- 按原样保留每部分开头的标记行，每个标记只输出一次且顺序不变；某部分的要点全部重复时只保留标记行
+- 除各部分的内容外不要输出其他说明
+
+%s`
+
+const englishDedupPrompt = `The following are notes generated, in chronological order, for consecutive parts of the same video. Because each part was summarized independently, later parts often repeat points already made earlier. Remove the repetition:
+- Delete points in later parts that say the same or nearly the same thing as an earlier point; if such a point adds a detail, fold it into the earliest occurrence
+- Do not add content, do not rewrite points that are not repeated, and keep the original formatting
+- Keep the marker line at the start of each part exactly as given, once each and in the same order; if every point of a part is a repeat, output only its marker line
+- Output nothing besides the parts
+
+%s`
+
+// 单次去重请求中各部分摘要的总长度上限（字节），超出时分组去重，跨组的重复不再处理
+const dedupBudget = 2 * reduceBudget
+
+// dedupSections 让模型删除后面部分中重复前面部分的要点，各部分的时间、章节等信息保持不变；
+// 模型返回的内容无法按部分拆分时保留原摘要
+func dedupSections(ctx context.Context, summarizer Summarizer, limiter *rateLimiter, config *Config, sections []Section, opts SummarizeOptions) ([]Section, error) {
+	summaries := make([]string, len(sections))
+	for i, section := range sections {
+		summaries[i] = section.Summary
+	}
+
+	var result []string
+	for _, group := range groupTexts(summaries, dedupBudget) {
+		if len(group) < 2 {
+			result = append(result, group...)
+			continue
+		}
+		deduped, err := dedupOnce(ctx, summarizer, limiter, config, group, opts)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, deduped...)
+	}
+
+	var kept []Section
+	removed := 0
+	for i, section := range sections {
+		section.Summary = result[i]
+		// 要点全部重复的部分不再单独输出，章节保留标题以免目录断档
+		if strings.TrimSpace(section.Summary) == "" && section.Title == "" {
+			removed++
+			continue
+		}
+		kept = append(kept, section)
+	}
+	if removed > 0 {
+		infof("去重后删除了%d个完全重复的部分", removed)
+	}
+	return kept, nil
+}
+
+func dedupOnce(ctx context.Context, summarizer Summarizer, limiter *rateLimiter, config *Config, summaries []string, opts SummarizeOptions) ([]string, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	parts := make([]string, len(summaries))
+	for i, summary := range summaries {
+		parts[i] = fmt.Sprintf(opts.prompts.Part, i+1) + "\n" + strings.TrimSpace(summary)
+	}
+	prompt := fmt.Sprintf(opts.prompts.Dedup, strings.Join(parts, "\n\n")) +
+		opts.prompts.formatHint(opts.Format) + opts.prompts.languageHint(opts.Language) + opts.prompts.glossaryHint(opts.Glossary)
+
+	result, err := summarizer.Complete(ctx, prompt, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("去除重复要点失败: %w", err)
+	}
+
+	deduped, ok := splitParts(result.Text, opts.prompts.Part, len(summaries))
+	if !ok {
+		warnf("去重结果无法按部分拆分，保留原摘要")
+		return summaries, nil
+	}
+	return deduped, nil
+}
+
+// splitParts 按 part 格式的标记行拆分模型的输出，标记必须为 1..n 且各出现一次
+func splitParts(text, part string, n int) ([]string, bool) {
+	before, after, _ := strings.Cut(part, "%d")
+	marker := regexp.MustCompile(`(?m)^\s*` + regexp.QuoteMeta(strings.TrimSpace(before)) + `\s*(\d+)\s*` +
+		regexp.QuoteMeta(strings.TrimSpace(after)) + `\s*$`)
+
+	matches := marker.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) != n {
+		return nil, false
+	}
+	parts := make([]string, n)
+	for i, m := range matches {
+		if idx, _ := strconv.Atoi(text[m[2]:m[3]]); idx != i+1 {
+			return nil, false
+		}
+		end := len(text)
+		if i+1 < n {
+			end = matches[i+1][0]
+		}
+		parts[i] = strings.TrimSpace(text[m[1]:end])
+	}
+	return parts, true
+}
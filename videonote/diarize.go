@@ -0,0 +1,119 @@
+package videonote
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpeakerTurn 为说话人分离结果中的一段连续发言
+type SpeakerTurn struct {
+	Start   time.Duration
+	End     time.Duration
+	Speaker string
+}
+
+// Diarizer 对音频进行说话人分离
+type Diarizer interface {
+	Diarize(ctx context.Context, audioPath string) ([]SpeakerTurn, error)
+}
+
+// commandDiarizer 调用外部命令进行说话人分离（如基于 pyannote 的脚本），
+// 音频路径作为最后一个参数传入，命令需在标准输出中打印 RTTM 格式的结果
+type commandDiarizer struct {
+	command []string
+}
+
+func newDiarizer(config *Config) (Diarizer, error) {
+	if len(config.DiarizeCommand) == 0 {
+		return nil, fmt.Errorf("未配置说话人分离命令，请在配置文件中设置 diarize_command")
+	}
+	return &commandDiarizer{command: config.DiarizeCommand}, nil
+}
+
+func (d *commandDiarizer) Diarize(ctx context.Context, audioPath string) ([]SpeakerTurn, error) {
+	args := append(append([]string{}, d.command[1:]...), audioPath)
+	cmd := exec.CommandContext(ctx, d.command[0], args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	debugCommand(cmd)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("说话人分离命令执行失败: %w\n输出: %s", err, stderr.String())
+	}
+	return parseRTTM(string(output))
+}
+
+// parseRTTM 解析 RTTM 格式的说话人分离结果，每行形如：
+// SPEAKER <file> <channel> <start> <duration> <NA> <NA> <speaker> <NA> <NA>
+func parseRTTM(content string) ([]SpeakerTurn, error) {
+	var turns []SpeakerTurn
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "SPEAKER" {
+			continue
+		}
+		if len(fields) < 8 {
+			return nil, fmt.Errorf("第%d行RTTM格式无效: %s", line, scanner.Text())
+		}
+
+		start, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("第%d行RTTM起始时间无效: %w", line, err)
+		}
+		duration, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("第%d行RTTM时长无效: %w", line, err)
+		}
+
+		turns = append(turns, SpeakerTurn{
+			Start:   secondsToDuration(start),
+			End:     secondsToDuration(start + duration),
+			Speaker: fields[7],
+		})
+	}
+	return turns, scanner.Err()
+}
+
+// assignSpeakers 为每个转录片段标注与其时间重叠最多的说话人；
+// 原始标签按首次出现的顺序重命名为 Speaker 1、Speaker 2……
+func assignSpeakers(segments []Segment, turns []SpeakerTurn) {
+	names := map[string]string{}
+	for i := range segments {
+		speaker := bestSpeaker(segments[i], turns)
+		if speaker == "" {
+			continue
+		}
+		if _, ok := names[speaker]; !ok {
+			names[speaker] = fmt.Sprintf("Speaker %d", len(names)+1)
+		}
+		segments[i].Speaker = names[speaker]
+	}
+}
+
+// bestSpeaker 返回与片段重叠时间最长的说话人；没有重叠时取时间上最近的发言
+func bestSpeaker(seg Segment, turns []SpeakerTurn) string {
+	var best string
+	var bestOverlap time.Duration
+	nearest, nearestGap := "", time.Duration(-1)
+	for _, turn := range turns {
+		overlap := min(seg.End, turn.End) - max(seg.Start, turn.Start)
+		if overlap > bestOverlap {
+			best, bestOverlap = turn.Speaker, overlap
+		}
+
+		gap := max(turn.Start-seg.End, seg.Start-turn.End)
+		if nearestGap < 0 || gap < nearestGap {
+			nearest, nearestGap = turn.Speaker, gap
+		}
+	}
+	if best != "" {
+		return best
+	}
+	return nearest
+}
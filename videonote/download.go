@@ -0,0 +1,48 @@
+package videonote
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func isURL(input string) bool {
+	return strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://")
+}
+
+// downloadMedia 使用 yt-dlp 将 URL 对应的媒体下载到 dir，返回下载后的本地文件路径
+func downloadMedia(ctx context.Context, url, dir, cookiesPath string) (string, error) {
+	if _, err := exec.LookPath("yt-dlp"); err != nil {
+		return "", markError(ErrDependency, fmt.Errorf("未找到yt-dlp，无法下载在线视频，请先安装: https://github.com/yt-dlp/yt-dlp#installation"))
+	}
+
+	args := []string{
+		"--no-playlist",
+		"--embed-chapters",
+		"-f", "bestaudio/best",
+		"-o", filepath.Join(dir, "%(title)s.%(ext)s"),
+		"--print", "after_move:filepath",
+	}
+	if cookiesPath != "" {
+		args = append(args, "--cookies", cookiesPath)
+	}
+	args = append(args, url)
+
+	cmd := exec.CommandContext(ctx, "yt-dlp", args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	debugCommand(cmd)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("yt-dlp执行失败: %w\n输出: %s", err, stderr.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	path := strings.TrimSpace(lines[len(lines)-1])
+	if path == "" {
+		return "", fmt.Errorf("yt-dlp未返回下载文件路径")
+	}
+	return path, nil
+}
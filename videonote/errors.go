@@ -0,0 +1,81 @@
+package videonote
+
+import (
+	"errors"
+	"net/http"
+	"os/exec"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// 错误类别，可用 errors.Is 判断，命令行据此选择退出码
+var (
+	// ErrConfig 表示配置文件或环境变量无效
+	ErrConfig = errors.New("配置错误")
+	// ErrDependency 表示缺少 ffmpeg、yt-dlp 等外部程序
+	ErrDependency = errors.New("缺少依赖")
+	// ErrAuth 表示接口拒绝了API密钥
+	ErrAuth = errors.New("认证失败")
+	// ErrPartial 表示批量处理中部分输入失败，或笔记中部分内容生成失败
+	ErrPartial = errors.New("部分输入处理失败")
+	// ErrNoSpeech 表示转录结果为空，视频或音频中没有可识别的语音
+	ErrNoSpeech = errors.New("没有检测到语音")
+	// ErrMedia 表示输入文件损坏、不是音视频或没有音频流
+	ErrMedia = errors.New("无法处理的媒体文件")
+)
+
+// kindError 为错误标记类别，错误信息保持不变
+type kindError struct {
+	err  error
+	kind error
+}
+
+func (e *kindError) Error() string        { return e.err.Error() }
+func (e *kindError) Unwrap() error        { return e.err }
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+func markError(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{err: err, kind: kind}
+}
+
+// isAuthError 判断 err 是否为接口返回的 401/403
+func isAuthError(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return isAuthStatus(apiErr.HTTPStatusCode)
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return isAuthStatus(reqErr.HTTPStatusCode)
+	}
+	return false
+}
+
+func isAuthStatus(code int) bool {
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+// ErrorKind 返回 err 所属的类别 (ErrConfig、ErrDependency、ErrAuth、ErrPartial、ErrNoSpeech 或 ErrMedia)，无法归类时返回 nil
+func ErrorKind(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	// 部分失败时汇总的错误中可能包含其他类别，优先报告部分失败
+	case errors.Is(err, ErrPartial):
+		return ErrPartial
+	case errors.Is(err, ErrConfig):
+		return ErrConfig
+	case errors.Is(err, ErrDependency), errors.Is(err, exec.ErrNotFound):
+		return ErrDependency
+	case errors.Is(err, ErrAuth), isAuthError(err):
+		return ErrAuth
+	case errors.Is(err, ErrNoSpeech):
+		return ErrNoSpeech
+	case errors.Is(err, ErrMedia):
+		return ErrMedia
+	}
+	return nil
+}
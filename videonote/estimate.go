@@ -0,0 +1,139 @@
+package videonote
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+	"unicode/utf8"
+)
+
+// ModelPrice 为模型的单价（美元），对话模型按 token 计费，转录模型按音频时长计费
+type ModelPrice struct {
+	// Input 为每百万输入 token 的价格
+	Input float64 `json:"input"`
+	// Output 为每百万输出 token 的价格
+	Output float64 `json:"output"`
+	// PerMinute 为转录模型每分钟音频的价格
+	PerMinute float64 `json:"per_minute"`
+}
+
+// 内置价格表，可通过配置文件中的 pricing 覆盖或补充
+var defaultPricing = map[string]ModelPrice{
+	"whisper-1":              {PerMinute: 0.006},
+	"gpt-4o-transcribe":      {PerMinute: 0.006},
+	"gpt-4o-mini-transcribe": {PerMinute: 0.003},
+	"gpt-3.5-turbo":          {Input: 0.5, Output: 1.5},
+	"gpt-4":                  {Input: 30, Output: 60},
+	"gpt-4-turbo":            {Input: 10, Output: 30},
+	"gpt-4o":                 {Input: 2.5, Output: 10},
+	"gpt-4o-mini":            {Input: 0.15, Output: 0.6},
+	"gpt-4.1":                {Input: 2, Output: 8},
+	"gpt-4.1-mini":           {Input: 0.4, Output: 1.6},
+	"gpt-4.1-nano":           {Input: 0.1, Output: 0.4},
+}
+
+const (
+	// 按正常语速估算每分钟语音转录出的 token 数
+	estimatedTokensPerMinute = 250
+	// 内置提示词本身大约占用的 token 数
+	estimatedPromptTokens = 60
+)
+
+func (c *Config) modelPrice(model string) (ModelPrice, bool) {
+	if price, ok := c.Pricing[model]; ok {
+		return price, true
+	}
+	price, ok := defaultPricing[model]
+	return price, ok
+}
+
+// Estimate 为一次运行预估的用量与费用
+type Estimate struct {
+	Duration     time.Duration
+	Chunks       int
+	InputTokens  int
+	OutputTokens int
+
+	TranscribeModel string
+	TranscribeCost  float64
+	TranscribeKnown bool
+
+	SummarizeModel string
+	SummarizeCost  float64
+	SummarizeKnown bool
+}
+
+// estimateFromDuration 在尚未转录时按音频时长估算转录文本长度与费用
+func estimateFromDuration(config *Config, duration time.Duration, ratio float64) Estimate {
+	minutes := duration.Minutes()
+	textTokens := int(minutes * estimatedTokensPerMinute)
+	chunks := int(math.Ceil(float64(textTokens) / float64(config.chunkTokens())))
+
+	est := Estimate{
+		Duration:        duration,
+		TranscribeModel: config.TranscribeModel,
+	}
+	if price, ok := config.modelPrice(config.TranscribeModel); ok {
+		est.TranscribeCost = minutes * price.PerMinute
+		est.TranscribeKnown = true
+	}
+	est.addSummarize(config, chunks, textTokens, ratio)
+	return est
+}
+
+// EstimateFromText 按已有的转录文本估算摘要阶段的用量与费用
+func EstimateFromText(config *Config, transcript *Transcript, ratio float64) Estimate {
+	count := tokenCounter(config.SummarizeModel)
+	chunks := transcript.chunks(config.chunkTokens(), count)
+
+	var est Estimate
+	est.addSummarize(config, len(chunks), count(transcript.Text), ratio)
+	return est
+}
+
+func (e *Estimate) addSummarize(config *Config, chunks, textTokens int, ratio float64) {
+	e.Chunks = chunks
+	e.InputTokens = textTokens + chunks*estimatedPromptTokens
+	e.OutputTokens = int(float64(textTokens) * ratio)
+	e.SummarizeModel = config.SummarizeModel
+	if price, ok := config.modelPrice(config.SummarizeModel); ok {
+		e.SummarizeCost = (float64(e.InputTokens)*price.Input + float64(e.OutputTokens)*price.Output) / 1e6
+		e.SummarizeKnown = true
+	}
+}
+
+// estimateTokens 在无法加载分词器时粗略估算文本的 token 数：英文约 4 个字符一个 token，中日韩文字约一字一个 token
+func estimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return ascii/4 + other
+}
+
+func (e Estimate) Print(w io.Writer) {
+	fmt.Fprintln(w, "预估用量 (仅供参考，未调用摘要接口):")
+	if e.Duration > 0 {
+		fmt.Fprintf(w, "  音频时长:     %s\n", formatTimestamp(e.Duration))
+		fmt.Fprintf(w, "  转录费用:     %s (%s)\n", formatCost(e.TranscribeCost, e.TranscribeKnown), e.TranscribeModel)
+	}
+	fmt.Fprintf(w, "  文本块数:     %d\n", e.Chunks)
+	fmt.Fprintf(w, "  输入tokens:   ~%d\n", e.InputTokens)
+	fmt.Fprintf(w, "  输出tokens:   ~%d\n", e.OutputTokens)
+	fmt.Fprintf(w, "  摘要费用:     %s (%s)\n", formatCost(e.SummarizeCost, e.SummarizeKnown), e.SummarizeModel)
+	if (e.Duration == 0 || e.TranscribeKnown) && e.SummarizeKnown {
+		fmt.Fprintf(w, "  合计:         %s\n", formatCost(e.TranscribeCost+e.SummarizeCost, true))
+	}
+}
+
+func formatCost(cost float64, known bool) string {
+	if !known {
+		return "未知 (可在配置文件 pricing 中设置该模型价格)"
+	}
+	return fmt.Sprintf("$%.4f", cost)
+}
@@ -0,0 +1,135 @@
+package videonote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Extractor 为 -extract 可选的结构化提取项
+type Extractor string
+
+const (
+	ExtractTopics   Extractor = "topics"
+	ExtractEntities Extractor = "entities"
+	ExtractActions  Extractor = "actions"
+)
+
+// extractorTitles 为各提取项在笔记中的标题
+var extractorTitles = map[Extractor]string{
+	ExtractTopics:   "关键主题",
+	ExtractEntities: "命名实体",
+	ExtractActions:  "行动事项",
+}
+
+const extractPrompt = `请从以下视频内容中提取%s。每行输出一项，以“- ”开头，不要编号，不要输出其他说明；没有相关内容时不要输出任何内容。
+
+%s`
+
+const englishExtractPrompt = `Extract %s from the following video content. Output one item per line starting with "- ", without numbering or any other explanation; if there is nothing relevant, output nothing.
+
+%s`
+
+// ParseExtractors 解析逗号分隔的 -extract，忽略重复项并保持顺序
+func ParseExtractors(s string) ([]Extractor, error) {
+	var extractors []Extractor
+	seen := make(map[Extractor]bool)
+	for _, name := range strings.Split(s, ",") {
+		e := Extractor(strings.ToLower(strings.TrimSpace(name)))
+		if e == "" || seen[e] {
+			continue
+		}
+		if _, ok := extractorTitles[e]; !ok {
+			return nil, fmt.Errorf("不支持的提取项: %s (可选: topics, entities, actions)", name)
+		}
+		seen[e] = true
+		extractors = append(extractors, e)
+	}
+	return extractors, nil
+}
+
+// CheckExtract 检查 -extract 是否适用于该格式；问答卡片没有附加内容的位置
+func CheckExtract(extractors []Extractor, f Format) error {
+	if len(extractors) > 0 && f.isFlashcards() {
+		return fmt.Errorf("-extract 不适用于 %s 格式", f)
+	}
+	return nil
+}
+
+// extraction 为一个提取项的结果
+type extraction struct {
+	Kind  Extractor
+	Items []string
+}
+
+// runExtractors 依次请求各提取项；转录能一次放入请求时从转录中提取，否则从各部分摘要中提取
+func runExtractors(ctx context.Context, summarizer Summarizer, limiter *rateLimiter, config *Config, transcript *Transcript, sections []Section, opts SummarizeOptions) ([]extraction, error) {
+	source := transcript.Text
+	if count := tokenCounter(config.SummarizeModel); count(source) > config.chunkTokens() {
+		summaries := make([]string, len(sections))
+		for i, section := range sections {
+			summaries[i] = strings.TrimSpace(section.Summary)
+		}
+		source = strings.Join(summaries, "\n\n")
+		debugf("转录超过单块上限，从摘要中提取")
+	}
+
+	var results []extraction
+	for _, e := range opts.Extractors {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		prompt := fmt.Sprintf(opts.prompts.Extract, opts.prompts.Extractors[e], strings.TrimSpace(source)) +
+			opts.prompts.languageHint(opts.Language) + opts.prompts.glossaryHint(opts.Glossary)
+		result, err := summarizer.Complete(ctx, prompt, 0, nil)
+		switch {
+		case errors.Is(err, errEmptyResponse):
+			// 没有相关内容
+		case errors.Is(err, errContentFiltered):
+			warnf("提取%s被内容过滤拦截，已跳过: %v", extractorTitles[e], err)
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("提取%s失败: %w", extractorTitles[e], err)
+		}
+		results = append(results, extraction{Kind: e, Items: parseItems(result.Text)})
+	}
+	return results, nil
+}
+
+// listMarker 匹配行首的列表符号或编号，如 "- "、"1. "、"2) "
+var listMarker = regexp.MustCompile(`^(?:[-*•]|\d+[.)、])\s*`)
+
+// parseItems 将模型返回的列表拆分为各项，去掉列表符号和编号
+func parseItems(text string) []string {
+	var items []string
+	for _, line := range strings.Split(text, "\n") {
+		line = listMarker.ReplaceAllString(strings.TrimSpace(line), "")
+		if line = strings.TrimSpace(line); line != "" {
+			items = append(items, line)
+		}
+	}
+	return items
+}
+
+// renderExtractions 将提取结果输出为笔记末尾的独立部分
+func renderExtractions(extractions []extraction, format Format) string {
+	var b strings.Builder
+	for _, e := range extractions {
+		items := make([]string, len(e.Items))
+		for i, item := range e.Items {
+			items[i] = "- " + item
+		}
+		if len(items) == 0 {
+			items = []string{"(无)"}
+		}
+		// 与 renderText 和 renderMarkdown 的结尾方式保持一致
+		if format == FormatMarkdown {
+			fmt.Fprintf(&b, "\n## %s\n\n%s\n", extractorTitles[e.Kind], strings.Join(items, "\n"))
+		} else {
+			fmt.Fprintf(&b, "\n\n========== %s ==========\n\n%s", extractorTitles[e.Kind], strings.Join(items, "\n"))
+		}
+	}
+	return b.String()
+}
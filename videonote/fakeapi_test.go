@@ -0,0 +1,293 @@
+package videonote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// 测试时只输出错误日志，避免进度和警告淹没测试结果
+func TestMain(m *testing.M) {
+	if err := SetupLogging("error", false, true); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeAPI 模拟的 OpenAI 接口路径
+const (
+	transcriptionsPath = "/v1/audio/transcriptions"
+	completionsPath    = "/v1/chat/completions"
+)
+
+// fakeAPI 模拟 OpenAI 的转录和对话接口，测试通过 Config.BaseURL 将客户端指向它。
+// 各字段需要在发出请求之前设置
+type fakeAPI struct {
+	*httptest.Server
+
+	// Transcript 为转录接口返回的文本；Segments 为 verbose_json 返回的片段，为空时整段作为一个片段
+	Transcript string
+	Segments   []Segment
+	// Complete 根据提示词生成对话接口的回复，为 nil 时依次返回 "摘要1"、"摘要2" ……
+	Complete func(prompt string) string
+	// Delay 不为 nil 时每个对话请求先等待返回的时长，用于模拟乱序完成和并发
+	Delay func(prompt string) time.Duration
+	// RateLimited 为各接口在成功之前返回 429 的次数
+	RateLimited map[string]int
+	// Malformed 为 true 的接口返回无法解析的 JSON
+	Malformed map[string]bool
+
+	mu          sync.Mutex
+	calls       map[string]int
+	formats     []string
+	files       []string
+	prompts     []string
+	finished    []string
+	inflight    int
+	maxInflight int
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	t.Helper()
+	api := &fakeAPI{
+		Transcript:  "这是一段测试用的转录文本。",
+		RateLimited: map[string]int{},
+		Malformed:   map[string]bool{},
+		calls:       map[string]int{},
+	}
+	api.Server = httptest.NewServer(http.HandlerFunc(api.serve))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// config 返回指向模拟接口的配置；ffmpeg 指向不存在的路径，读取时长和章节会失败并被忽略
+func (api *fakeAPI) config(t *testing.T) *Config {
+	t.Helper()
+	return &Config{
+		OpenAIAPIKey:    "test-key",
+		BaseURL:         api.URL + "/v1",
+		TranscribeModel: openai.Whisper1,
+		SummarizeModel:  openai.GPT4oMini,
+		MaxAttempts:     3,
+		FFmpegPath:      filepath.Join(t.TempDir(), "ffmpeg"),
+	}
+}
+
+// Calls 返回某个接口收到的请求数，包括被限流的请求
+func (api *fakeAPI) Calls(path string) int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.calls[path]
+}
+
+// Formats 返回各转录请求的 response_format
+func (api *fakeAPI) Formats() []string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return append([]string(nil), api.formats...)
+}
+
+// Files 返回转录接口收到的音频文件名，按收到的顺序
+func (api *fakeAPI) Files() []string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return append([]string(nil), api.files...)
+}
+
+// Prompts 返回对话接口收到的提示词，按收到的顺序
+func (api *fakeAPI) Prompts() []string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return append([]string(nil), api.prompts...)
+}
+
+// Finished 返回对话接口已回复的提示词，按回复的顺序
+func (api *fakeAPI) Finished() []string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return append([]string(nil), api.finished...)
+}
+
+// MaxInflight 返回同时进行中的请求数的最大值
+func (api *fakeAPI) MaxInflight() int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.maxInflight
+}
+
+func (api *fakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	api.calls[r.URL.Path]++
+	n := api.calls[r.URL.Path]
+	api.inflight++
+	api.maxInflight = max(api.maxInflight, api.inflight)
+	api.mu.Unlock()
+	defer func() {
+		api.mu.Lock()
+		api.inflight--
+		api.mu.Unlock()
+	}()
+
+	if n <= api.RateLimited[r.URL.Path] {
+		writeAPIError(w, http.StatusTooManyRequests, "rate_limit_exceeded", "Rate limit reached")
+		return
+	}
+	switch r.URL.Path {
+	case transcriptionsPath:
+		api.transcribe(w, r)
+	case completionsPath:
+		api.complete(w, r)
+	default:
+		writeAPIError(w, http.StatusNotFound, "not_found", "unknown endpoint "+r.URL.Path)
+	}
+}
+
+func (api *fakeAPI) transcribe(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	format := r.FormValue("response_format")
+	api.mu.Lock()
+	api.formats = append(api.formats, format)
+	if _, header, err := r.FormFile("file"); err == nil {
+		api.files = append(api.files, header.Filename)
+	}
+	api.mu.Unlock()
+
+	if api.Malformed[transcriptionsPath] {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"text": "未结束的`)
+		return
+	}
+	switch format {
+	case "", string(openai.AudioResponseFormatText):
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, api.Transcript)
+	case string(openai.AudioResponseFormatJSON):
+		writeJSONBody(w, map[string]any{"text": api.Transcript})
+	case string(openai.AudioResponseFormatVerboseJSON):
+		segments := api.Segments
+		if len(segments) == 0 {
+			segments = []Segment{{Start: 0, End: 5 * time.Second, Text: api.Transcript}}
+		}
+		var list []map[string]any
+		for i, seg := range segments {
+			list = append(list, map[string]any{
+				"id": i, "start": seg.Start.Seconds(), "end": seg.End.Seconds(), "text": seg.Text,
+				"avg_logprob": -0.2, "no_speech_prob": 0.01,
+			})
+		}
+		writeJSONBody(w, map[string]any{
+			"task": "transcribe", "language": "chinese", "text": api.Transcript, "segments": list,
+		})
+	default:
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "unsupported response_format "+format)
+	}
+}
+
+func (api *fakeAPI) complete(w http.ResponseWriter, r *http.Request) {
+	var req openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) == 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "invalid request body")
+		return
+	}
+	prompt := req.Messages[len(req.Messages)-1].Content
+	api.mu.Lock()
+	api.prompts = append(api.prompts, prompt)
+	n := len(api.prompts)
+	api.mu.Unlock()
+
+	if api.Delay != nil {
+		select {
+		case <-time.After(api.Delay(prompt)):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if api.Malformed[completionsPath] {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id": "chatcmpl-test", "choices": [`)
+		return
+	}
+	content := fmt.Sprintf("摘要%d", n)
+	if api.Complete != nil {
+		content = api.Complete(prompt)
+	}
+	api.mu.Lock()
+	api.finished = append(api.finished, prompt)
+	api.mu.Unlock()
+	if req.Stream {
+		writeStream(w, req, content)
+		return
+	}
+	writeJSONBody(w, map[string]any{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   req.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]any{"role": "assistant", "content": content},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]any{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+	})
+}
+
+// writeStream 以 SSE 逐字返回 content；请求 include_usage 时与 OpenAI 相同，
+// 在 [DONE] 之前追加一个 choices 为空、只含用量的数据块
+func writeStream(w http.ResponseWriter, req openai.ChatCompletionRequest, content string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	chunk := func(choices []map[string]any, usage any) {
+		data, _ := json.Marshal(map[string]any{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   req.Model,
+			"choices": choices,
+			"usage":   usage,
+		})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	for _, r := range content {
+		chunk([]map[string]any{{"index": 0, "delta": map[string]any{"content": string(r)}}}, nil)
+	}
+	chunk([]map[string]any{{"index": 0, "delta": map[string]any{}, "finish_reason": "stop"}}, nil)
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		chunk([]map[string]any{}, map[string]any{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15})
+	}
+	io.WriteString(w, "data: [DONE]\n\n")
+}
+
+func writeJSONBody(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"message": message, "type": code, "code": code},
+	})
+}
+
+// writeTestFile 在临时目录中写入文件并返回路径
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,97 @@
+package videonote
+
+import (
+	"fmt"
+	"strings"
+)
+
+// managedFFmpegOptions 为提取音频时由程序管理的 ffmpeg 选项，-ffmpeg-args 中不能出现，
+// 值为应改用的参数
+var managedFFmpegOptions = map[string]string{
+	"-i":              "",
+	"-y":              "",
+	"-n":              "",
+	"-vn":             "",
+	"-map":            "-audio-track",
+	"-ss":             "-start",
+	"-to":             "-end",
+	"-t":              "-end",
+	"-f":              "-audio-codec",
+	"-c":              "-audio-codec",
+	"-codec":          "-audio-codec",
+	"-c:a":            "-audio-codec",
+	"-codec:a":        "-audio-codec",
+	"-acodec":         "-audio-codec",
+	"-ar":             "-sample-rate",
+	"-ac":             "-channels",
+	"-b:a":            "-bitrate",
+	"-ab":             "-bitrate",
+	"-filter_complex": "",
+}
+
+// audioFilterOptions 会覆盖 -trim-silence 和 -normalize 设置的音频滤镜
+var audioFilterOptions = []string{"-af", "-filter:a"}
+
+// ParseFFmpegArgs 按 shell 的规则拆分 -ffmpeg-args，支持用单引号或双引号包含空格
+func ParseFFmpegArgs(s string) ([]string, error) {
+	return splitArgs(s, "-ffmpeg-args")
+}
+
+// splitArgs 按 shell 的规则拆分命令行参数，不展开变量和通配符；flag 用于错误提示
+func splitArgs(s, flag string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("%s 中的引号不匹配", flag)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// validateFFmpegArgs 检查额外参数不会改变输入、输出和程序管理的编码设置；
+// 不以 - 开头的参数必须是前一个选项的值，否则 ffmpeg 会把它当作额外的输出文件
+func validateFFmpegArgs(args []string, hasFilters bool) error {
+	for i, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			if i == 0 || !strings.HasPrefix(args[i-1], "-") {
+				return fmt.Errorf("-ffmpeg-args 中的 %q 不是选项的值，ffmpeg 会将其视为输出文件", arg)
+			}
+			continue
+		}
+		if use, ok := managedFFmpegOptions[arg]; ok {
+			if use != "" {
+				return fmt.Errorf("-ffmpeg-args 不能包含 %s，请改用 %s", arg, use)
+			}
+			return fmt.Errorf("-ffmpeg-args 不能包含由程序管理的 %s", arg)
+		}
+		for _, opt := range audioFilterOptions {
+			if arg == opt && hasFilters {
+				return fmt.Errorf("-ffmpeg-args 中的 %s 会覆盖 -trim-silence 或 -normalize 使用的滤镜，不能同时使用", arg)
+			}
+		}
+	}
+	return nil
+}
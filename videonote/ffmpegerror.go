@@ -0,0 +1,80 @@
+package videonote
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// ffmpegTailLines 为无法识别的错误附带的 ffmpeg 输出行数，错误原因通常在最后几行
+const ffmpegTailLines = 5
+
+// ffmpegFailure 为 ffmpeg 或 ffprobe 输出中常见的失败特征，message 为给用户的说明和建议
+type ffmpegFailure struct {
+	pattern *regexp.Regexp
+	message string
+}
+
+// ffmpegFailures 按顺序匹配，靠前的原因更具体
+var ffmpegFailures = []ffmpegFailure{
+	{regexp.MustCompile(`(?i)no such file or directory`), "文件不存在或路径无法访问"},
+	{regexp.MustCompile(`(?i)permission denied`), "没有读取该文件或写入输出目录的权限"},
+	{regexp.MustCompile(`(?i)no space left on device`), "磁盘空间不足，请清理临时目录或输出目录所在的磁盘"},
+	{regexp.MustCompile(`(?i)moov atom not found`), "MP4/MOV 文件不完整 (缺少索引信息)，通常是录制或下载没有正常结束，可以尝试重新下载或用其他工具修复"},
+	{regexp.MustCompile(`(?i)does not contain any stream|matches no streams|stream specifier .* matches no streams`), "文件中没有音频流，可能是无声视频或图片，可用 info 命令查看其中的流"},
+	{regexp.MustCompile(`(?i)invalid data found when processing input|could not find codec parameters|ebml header parsing failed`), "文件已损坏或不是支持的音视频格式"},
+	{regexp.MustCompile(`(?i)decoder .* not found|unknown decoder|could not find tag for codec`), "当前的 ffmpeg 不支持该文件的编码，请安装完整版的 ffmpeg"},
+	{regexp.MustCompile(`(?i)unknown encoder|encoder not found`), "当前的 ffmpeg 不支持所选的音频编码，请换用其他 -audio-codec 或安装完整版的 ffmpeg"},
+	{regexp.MustCompile(`(?i)unrecognized option|option not found|error splitting the argument list`), "ffmpeg 不认识其中的某个选项，请检查 -ffmpeg-args"},
+}
+
+// ffmpegError 将 ffmpeg 或 ffprobe 的失败转换为易懂的错误：识别出常见的原因时给出说明和建议，
+// 否则附上输出的最后几行。-verbose (日志级别为 debug) 时总是附上完整的输出。
+// output 为 nil 时使用 exec.ExitError 中收集的标准错误
+func ffmpegError(tool, path string, err error, output []byte) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		// 没有运行起来，如找不到可执行文件，保留原始错误以便归类
+		return fmt.Errorf("%s执行失败: %w", tool, err)
+	}
+	if output == nil {
+		output = exitErr.Stderr
+	}
+	text := strings.TrimSpace(string(output))
+
+	verbose := logLevel.Level() <= slog.LevelDebug
+	detail := ""
+	if verbose && text != "" {
+		detail = fmt.Sprintf("\n%s的完整输出:\n%s", tool, text)
+	}
+	for _, f := range ffmpegFailures {
+		if f.pattern.MatchString(text) {
+			return markError(ErrMedia, fmt.Errorf("%s 无法处理: %s%s", path, f.message, detail))
+		}
+	}
+
+	if !verbose {
+		detail = ""
+		if tail := lastLines(text, ffmpegTailLines); tail != "" {
+			detail = fmt.Sprintf("\n%s的输出 (使用 -verbose 查看完整输出):\n%s", tool, tail)
+		}
+	}
+	return fmt.Errorf("%s处理 %s 失败: %w%s", tool, path, err, detail)
+}
+
+// lastLines 返回 text 中最后 n 个非空行
+func lastLines(text string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,122 @@
+package videonote
+
+import (
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Flashcard 为一张问答卡片
+type Flashcard struct {
+	Question string
+	Answer   string
+}
+
+// 卡片中问题和答案的开头，兼容模型偶尔输出的中文标记、列表符号和加粗
+var (
+	questionPrefix = regexp.MustCompile(`^(?:[-*]\s*|\d+[.、]\s*)?(?:\*\*)?(?:Q|问题|问)\s*[:：](?:\*\*)?\s*`)
+	answerPrefix   = regexp.MustCompile(`^(?:[-*]\s*)?(?:\*\*)?(?:A|答案|答)\s*[:：](?:\*\*)?\s*`)
+)
+
+// parseFlashcards 从模型回复中解析 "Q: ... / A: ..." 形式的卡片，
+// 问题或答案跨多行时保留换行，缺少答案的卡片会被丢弃
+func parseFlashcards(text string) []Flashcard {
+	var cards []Flashcard
+	var card *Flashcard
+	var field *string
+
+	flush := func() {
+		if card != nil {
+			card.Question = strings.TrimSpace(card.Question)
+			card.Answer = strings.TrimSpace(card.Answer)
+			if card.Question != "" && card.Answer != "" {
+				cards = append(cards, *card)
+			}
+		}
+		card, field = nil, nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case questionPrefix.MatchString(trimmed):
+			flush()
+			card = &Flashcard{Question: questionPrefix.ReplaceAllString(trimmed, "")}
+			field = &card.Question
+		case card != nil && answerPrefix.MatchString(trimmed):
+			card.Answer = answerPrefix.ReplaceAllString(trimmed, "")
+			field = &card.Answer
+		case field != nil:
+			*field += "\n" + line
+		}
+	}
+	flush()
+	return cards
+}
+
+// sectionCards 解析各部分的卡片，某部分没有解析出卡片时给出警告
+func sectionCards(sections []Section) [][]Flashcard {
+	cards := make([][]Flashcard, len(sections))
+	for i, section := range sections {
+		cards[i] = parseFlashcards(section.Summary)
+		if len(cards[i]) == 0 {
+			warnf("第 %d 部分没有解析出问答卡片，模型回复可能不符合 Q:/A: 格式", i+1)
+		}
+	}
+	return cards
+}
+
+// renderFlashcardsMarkdown 按部分输出 Markdown 问答卡片
+func renderFlashcardsMarkdown(title string, sections []Section) string {
+	var b strings.Builder
+	if title != "" {
+		fmt.Fprintf(&b, "# %s\n", title)
+	}
+	for i, cards := range sectionCards(sections) {
+		section := sections[i]
+		if len(sections) > 1 || section.Timed {
+			heading := fmt.Sprintf("第 %d 部分", i+1)
+			if section.Title != "" {
+				heading = section.Title
+			}
+			if marker := section.marker(); marker != "" {
+				heading += " " + marker
+			}
+			fmt.Fprintf(&b, "\n## %s\n", heading)
+		}
+		for _, card := range cards {
+			fmt.Fprintf(&b, "\n**Q:** %s\n\n**A:** %s\n", card.Question, card.Answer)
+		}
+	}
+	return strings.TrimPrefix(b.String(), "\n")
+}
+
+// renderFlashcardsCSV 输出可导入 Anki 的 CSV，每行为 "问题,答案"；
+// 开头的 #separator、#html 为 Anki 2.1.54 起支持的导入说明，
+// 内容中的逗号、引号和换行由 encoding/csv 转义
+func renderFlashcardsCSV(sections []Section) (string, error) {
+	var b strings.Builder
+	b.WriteString("#separator:comma\n#html:false\n")
+	w := csv.NewWriter(&b)
+	for _, cards := range sectionCards(sections) {
+		for _, card := range cards {
+			if err := w.Write([]string{card.Question, card.Answer}); err != nil {
+				return "", fmt.Errorf("写入CSV失败: %w", err)
+			}
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", fmt.Errorf("写入CSV失败: %w", err)
+	}
+	return b.String(), nil
+}
+
+// CheckModeFormat 检查摘要模式与输出格式是否兼容；问答卡片按部分独立生成，不做整合
+func CheckModeFormat(mode Mode, format Format) error {
+	if mode == ModeMapReduce && format.isFlashcards() {
+		return fmt.Errorf("-format %s 不支持 -mode map-reduce", format)
+	}
+	return nil
+}
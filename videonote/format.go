@@ -0,0 +1,283 @@
+package videonote
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Format 表示笔记的输出格式
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatMarkdown Format = "md"
+	FormatSRT      Format = "srt"
+	FormatVTT      Format = "vtt"
+	FormatJSON     Format = "json"
+	// FormatFlashcards 和 FormatFlashcardsCSV 输出问答卡片，分别为 Markdown 和可导入 Anki 的 CSV
+	FormatFlashcards    Format = "flashcards"
+	FormatFlashcardsCSV Format = "flashcards-csv"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case FormatText, "txt":
+		return FormatText, nil
+	case FormatMarkdown, "markdown":
+		return FormatMarkdown, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatFlashcards:
+		return FormatFlashcards, nil
+	case FormatFlashcardsCSV, "anki":
+		return FormatFlashcardsCSV, nil
+	default:
+		return "", fmt.Errorf("不支持的输出格式: %s (可选: text, md, json, flashcards, flashcards-csv)", s)
+	}
+}
+
+// ParseTranscriptFormat 解析转录结果的输出格式
+func ParseTranscriptFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case FormatText, "txt":
+		return FormatText, nil
+	case FormatSRT:
+		return FormatSRT, nil
+	case FormatVTT:
+		return FormatVTT, nil
+	default:
+		return "", fmt.Errorf("不支持的转录格式: %s (可选: txt, srt, vtt)", s)
+	}
+}
+
+// isSubtitle 表示该格式是否需要带时间戳的转录片段
+func (f Format) isSubtitle() bool {
+	return f == FormatSRT || f == FormatVTT
+}
+
+// isFlashcards 表示该格式输出问答卡片而不是摘要
+func (f Format) isFlashcards() bool {
+	return f == FormatFlashcards || f == FormatFlashcardsCSV
+}
+
+// CheckBOM 检查 -bom 是否适用于该格式；JSON 和 CSV 由程序读取，不应带 BOM
+func CheckBOM(f Format) error {
+	switch f {
+	case FormatText, FormatMarkdown, FormatFlashcards:
+		return nil
+	}
+	return fmt.Errorf("-bom 只适用于 text、md 和 flashcards 格式，不适用于 %s", f)
+}
+
+// Ext 返回该格式对应的默认文件扩展名
+func (f Format) Ext() string {
+	switch f {
+	case FormatMarkdown, FormatFlashcards:
+		return ".md"
+	case FormatFlashcardsCSV:
+		return ".csv"
+	case FormatSRT:
+		return ".srt"
+	case FormatVTT:
+		return ".vtt"
+	case FormatJSON:
+		return ".json"
+	default:
+		return ".txt"
+	}
+}
+
+// renderTranscript 按输出格式生成转录文件内容
+func renderTranscript(transcript *Transcript, format Format) string {
+	switch format {
+	case FormatSRT:
+		return renderSRT(transcript.Segments)
+	case FormatVTT:
+		return renderVTT(transcript.Segments)
+	default:
+		return transcript.Text
+	}
+}
+
+// Section 为笔记中的一个部分，Timed 为 true 时 Start 为该部分在视频中的起始时间；
+// 按章节生成时 Chapter 为章节序号，Title 为章节标题
+type Section struct {
+	Summary string
+	Start   time.Duration
+	Timed   bool
+	Chapter int
+	Title   string
+	// SourceLength 为该部分原文的字数，用于 -stats
+	SourceLength int
+}
+
+// mergeChapters 将同一章节被拆成的多个部分合并，保证每个章节只对应一个部分
+func mergeChapters(sections []Section) []Section {
+	var merged []Section
+	for _, section := range sections {
+		if n := len(merged); n > 0 && section.Chapter != 0 && merged[n-1].Chapter == section.Chapter {
+			merged[n-1].Summary = strings.TrimSpace(merged[n-1].Summary) + "\n\n" + strings.TrimSpace(section.Summary)
+			merged[n-1].SourceLength += section.SourceLength
+			continue
+		}
+		merged = append(merged, section)
+	}
+	return merged
+}
+
+func (s Section) marker() string {
+	if !s.Timed {
+		return ""
+	}
+	return "[" + formatTimestamp(s.Start) + "]"
+}
+
+// noteInfo 为组装笔记时使用的元信息，Transcript 不为空时附在笔记之后，TOC 为目录样式，
+// Extractions 为 -extract 的结果，附在摘要之后
+type noteInfo struct {
+	Title       string
+	Source      string
+	Model       string
+	Transcript  string
+	TOC         TOC
+	Extractions []extraction
+}
+
+// renderNotes 按输出格式组装各部分摘要
+func renderNotes(info noteInfo, sections []Section, format Format) (string, error) {
+	switch format {
+	case FormatMarkdown:
+		return renderMarkdown(info, sections) + renderExtractions(info.Extractions, format) + markdownTranscript(info.Transcript), nil
+	case FormatJSON:
+		return renderJSON(info, sections, time.Now())
+	case FormatFlashcards:
+		return renderFlashcardsMarkdown(info.Title, sections) + markdownTranscript(info.Transcript), nil
+	case FormatFlashcardsCSV:
+		return renderFlashcardsCSV(sections)
+	default:
+		return renderTOC(info.TOC, info, sections, format) + renderText(sections) +
+			renderExtractions(info.Extractions, format) + textTranscript(info.Transcript), nil
+	}
+}
+
+func textTranscript(transcript string) string {
+	if transcript == "" {
+		return ""
+	}
+	return "\n\n========== 完整转录 ==========\n\n" + strings.TrimSpace(transcript) + "\n"
+}
+
+// markdownTranscript 将完整转录放在可折叠的区块中，避免篇幅盖过笔记本身
+func markdownTranscript(transcript string) string {
+	if transcript == "" {
+		return ""
+	}
+	return "\n## 完整转录\n\n<details>\n<summary>展开完整转录</summary>\n\n" +
+		strings.TrimSpace(transcript) + "\n\n</details>\n"
+}
+
+// renderText 按顺序拼接各部分摘要，并在相邻部分之间插入带编号的分隔线
+func renderText(sections []Section) string {
+	var b strings.Builder
+	for i, section := range sections {
+		if i > 0 {
+			fmt.Fprintf(&b, "\n\n--- 第 %d 部分结束 ---\n\n", i)
+		}
+		if marker := section.marker(); marker != "" {
+			b.WriteString(marker + " ")
+		}
+		if section.Title != "" {
+			b.WriteString(section.Title + "\n")
+		}
+		b.WriteString(section.Summary)
+	}
+	return b.String()
+}
+
+func renderMarkdown(info noteInfo, sections []Section) string {
+	var b strings.Builder
+	if info.Title != "" {
+		fmt.Fprintf(&b, "# %s\n", info.Title)
+	}
+	toc := renderTOC(info.TOC, info, sections, FormatMarkdown)
+	b.WriteString(toc)
+	for i, section := range sections {
+		if len(sections) > 1 || section.Timed {
+			heading := fmt.Sprintf("第 %d 部分", i+1)
+			if section.Title != "" {
+				heading = section.Title
+			}
+			if marker := section.marker(); marker != "" {
+				heading += " " + marker
+			}
+			// 目录中的链接指向显式的锚点
+			if info.TOC == TOCMarkdown && toc != "" {
+				fmt.Fprintf(&b, "\n<a id=\"%s\"></a>\n", tocAnchor(i))
+			}
+			fmt.Fprintf(&b, "\n## %s\n", heading)
+		}
+		fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(section.Summary))
+	}
+	// 没有标题时不留空的 "# " 行，也不以空行开头
+	return strings.TrimPrefix(b.String(), "\n")
+}
+
+// Notes 为 -format json 输出的结构，字段名保持稳定供下游工具解析
+type Notes struct {
+	Source   string        `json:"source"`
+	Model    string        `json:"model"`
+	Sections []NoteSection `json:"sections"`
+	// Topics、Entities 和 ActionItems 为 -extract 的结果，未提取或没有结果时省略
+	Topics      []string  `json:"topics,omitempty"`
+	Entities    []string  `json:"entities,omitempty"`
+	ActionItems []string  `json:"action_items,omitempty"`
+	Transcript  string    `json:"transcript,omitempty"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// NoteSection 为 JSON 笔记中的一个部分，Start 为 HH:MM:SS 格式，无时间信息时省略
+type NoteSection struct {
+	Index   int    `json:"index"`
+	Title   string `json:"title,omitempty"`
+	Summary string `json:"summary"`
+	Start   string `json:"start,omitempty"`
+}
+
+func renderJSON(info noteInfo, sections []Section, now time.Time) (string, error) {
+	notes := Notes{
+		Source:      info.Source,
+		Model:       info.Model,
+		Sections:    make([]NoteSection, len(sections)),
+		Transcript:  strings.TrimSpace(info.Transcript),
+		GeneratedAt: now.UTC().Truncate(time.Second),
+	}
+	for _, e := range info.Extractions {
+		switch e.Kind {
+		case ExtractTopics:
+			notes.Topics = e.Items
+		case ExtractEntities:
+			notes.Entities = e.Items
+		case ExtractActions:
+			notes.ActionItems = e.Items
+		}
+	}
+	for i, section := range sections {
+		notes.Sections[i] = NoteSection{Index: i + 1, Title: section.Title, Summary: strings.TrimSpace(section.Summary)}
+		if section.Timed {
+			notes.Sections[i].Start = formatClock(section.Start)
+		}
+	}
+	data, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("编码JSON失败: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// formatClock 将时长格式化为固定的 HH:MM:SS
+func formatClock(d time.Duration) string {
+	total := int(d.Seconds())
+	return fmt.Sprintf("%02d:%02d:%02d", total/3600, total%3600/60, total%60)
+}
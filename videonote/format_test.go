@@ -0,0 +1,69 @@
+package videonote
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderTextSeparators(t *testing.T) {
+	sections := []Section{{Summary: "第一部分"}, {Summary: "第二部分"}, {Summary: "第三部分"}}
+	got := renderText(sections)
+	want := "第一部分\n\n--- 第 1 部分结束 ---\n\n第二部分\n\n--- 第 2 部分结束 ---\n\n第三部分"
+	if got != want {
+		t.Errorf("renderText 输出为\n%s\n期望\n%s", got, want)
+	}
+	if strings.Contains(got, "%d") {
+		t.Errorf("分隔线中的编号没有展开:\n%s", got)
+	}
+	if got := renderText(sections[:1]); got != "第一部分" {
+		t.Errorf("只有一个部分时不应有分隔线: %q", got)
+	}
+}
+
+func TestRenderNotesMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		sections []Section
+		want     string
+	}{
+		{
+			name:  "sections with headings and bullets",
+			title: "测试讲座",
+			sections: []Section{
+				{Summary: "- 要点一\n- 要点二\n"},
+				{Summary: "- 要点三", Title: "总结"},
+			},
+			want: "# 测试讲座\n\n## 第 1 部分\n\n- 要点一\n- 要点二\n\n## 总结\n\n- 要点三\n",
+		},
+		{
+			name:     "single section without heading",
+			title:    "测试讲座",
+			sections: []Section{{Summary: "- 唯一的要点"}},
+			want:     "# 测试讲座\n\n- 唯一的要点\n",
+		},
+		{
+			name:     "timed section",
+			title:    "测试讲座",
+			sections: []Section{{Summary: "- 开场", Start: 90 * time.Second, Timed: true}},
+			want:     "# 测试讲座\n\n## 第 1 部分 [01:30]\n\n- 开场\n",
+		},
+		{
+			name:     "empty title",
+			sections: []Section{{Summary: "- 要点一"}, {Summary: "- 要点二"}},
+			want:     "## 第 1 部分\n\n- 要点一\n\n## 第 2 部分\n\n- 要点二\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderNotes(noteInfo{Title: tt.title}, tt.sections, FormatMarkdown)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("renderNotes 输出为\n%s\n期望\n%s", got, tt.want)
+			}
+		})
+	}
+}
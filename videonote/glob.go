@@ -0,0 +1,111 @@
+package videonote
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// globRecursive 展开含 ** 的通配符，** 匹配任意层 (包括零层) 子目录；
+// filepath.Glob 不支持 **，因此从通配符之前的目录开始遍历，再逐级匹配相对路径
+func globRecursive(pattern string) ([]string, error) {
+	parts := strings.Split(filepath.ToSlash(pattern), "/")
+	n := 0
+	for n < len(parts) && !strings.ContainsAny(parts[n], "*?[") {
+		n++
+	}
+	root, rest := ".", parts[n:]
+	if n > 0 {
+		root = filepath.FromSlash(strings.Join(parts[:n], "/") + "/")
+	}
+	for _, part := range rest {
+		if _, err := filepath.Match(part, ""); err != nil {
+			return nil, fmt.Errorf("无效的通配符 %q: %w", pattern, err)
+		}
+	}
+
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("没有文件匹配 %q: 目录 %s 不存在", pattern, root)
+	}
+
+	var files []string
+	total := 0
+	err := walkFiles(root, func(path string) {
+		total++
+		rel, err := filepath.Rel(root, path)
+		if err == nil && matchParts(rest, strings.Split(filepath.ToSlash(rel), "/")) {
+			files = append(files, path)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取目录失败: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("没有文件匹配 %q: %s 下共有%d个文件，但都不符合该模式", pattern, root, total)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// matchParts 逐级匹配路径，** 可以匹配零个或多个目录
+func matchParts(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchParts(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, _ := filepath.Match(pattern[0], name[0])
+	return ok && matchParts(pattern[1:], name[1:])
+}
+
+// walkFiles 递归遍历 root 下的所有文件，会进入指向目录的符号链接；
+// 按真实路径记录已访问的目录，遇到符号链接循环时跳过而不是无限递归
+func walkFiles(root string, fn func(path string)) error {
+	visited := make(map[string]bool)
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		real, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return err
+		}
+		if visited[real] {
+			warnf("跳过已遍历过的目录 (符号链接循环或重复链接): %s", dir)
+			return nil
+		}
+		visited[real] = true
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			// os.Stat 会跟随符号链接，失效的链接直接跳过
+			info, err := os.Stat(path)
+			if err != nil {
+				debugf("跳过无法访问的文件 %s: %v", path, err)
+				continue
+			}
+			if info.IsDir() {
+				if err := walk(path); err != nil {
+					return err
+				}
+				continue
+			}
+			fn(path)
+		}
+		return nil
+	}
+	return walk(root)
+}
@@ -0,0 +1,288 @@
+package videonote
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// whisperPromptTokens 为 Whisper 提示文本的 token 上限，超出的部分只保留最后这些 token
+const whisperPromptTokens = 224
+
+// glossaryWord 匹配转录中的英文单词 (含数字和 C++、Node.js 这类连接符)
+var glossaryWord = regexp.MustCompile(`[\p{Latin}\p{N}]+(?:[-.'+#][\p{Latin}\p{N}+#]+)*\+*`)
+
+// LoadGlossary 读取术语表文件，每行一个术语，忽略空行和 # 开头的注释行
+func LoadGlossary(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取术语表失败: %w", err)
+	}
+	defer f.Close()
+
+	var terms []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		term := strings.Join(strings.Fields(strings.TrimPrefix(scanner.Text(), utf8BOM)), " ")
+		if term == "" || strings.HasPrefix(term, "#") || seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取术语表失败: %w", err)
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("术语表 %s 中没有术语", path)
+	}
+	return terms, nil
+}
+
+// glossaryPrompt 将术语附在 Whisper 提示文本之后；Whisper 只使用提示文本最后的 224 个 token，
+// 超出时舍弃排在后面的术语，保证 -prompt 和前面的术语不被截断
+func glossaryPrompt(prompt string, terms []string) string {
+	if len(terms) == 0 {
+		return prompt
+	}
+	count := tokenCounter(openai.Whisper1)
+	result := prompt
+	for i, term := range terms {
+		next := term
+		if result != "" {
+			sep := ", "
+			if i == 0 {
+				sep = "\n"
+			}
+			next = result + sep + term
+		}
+		if count(next) > whisperPromptTokens {
+			warnf("术语表超过Whisper提示文本的 %d token 上限，只有前 %d 个术语作为转录提示", whisperPromptTokens, i)
+			break
+		}
+		result = next
+	}
+	return result
+}
+
+// glossaryHint 返回要求模型按术语表拼写专有名词的提示词，terms 为空时不做要求
+func (p promptSet) glossaryHint(terms []string) string {
+	if len(terms) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(p.Glossary, strings.Join(terms, ", "))
+}
+
+// glossaryTerm 为按单词拆分的术语，key 为去掉空格和大小写后用于比较的形式
+type glossaryTerm struct {
+	text  string
+	words int
+	key   string
+	// parts 为术语中每个单词的小写形式
+	parts []string
+	// fixCase 为 true 时只有大小写不同也更正，如 pytorch -> PyTorch；
+	// 只有首字母大写的术语 (如 React) 可能与普通单词相同，不改大小写
+	fixCase bool
+}
+
+// correctGlossary 按术语表更正转录中术语的大小写、拆开或连写的形式和拼写错误，返回更正的次数；有片段时同时更正每个片段
+func correctGlossary(t *Transcript, terms []string) int {
+	var list []glossaryTerm
+	for _, term := range terms {
+		// 只更正由英文单词组成的术语，中文术语依靠转录提示
+		words := glossaryWord.FindAllString(term, -1)
+		if len(words) == 0 || strings.Join(words, " ") != term {
+			continue
+		}
+		rest := []rune(term)[1:]
+		parts := make([]string, len(words))
+		for i, word := range words {
+			parts[i] = strings.ToLower(word)
+		}
+		list = append(list, glossaryTerm{
+			text:    term,
+			words:   len(words),
+			key:     glossaryKey(words),
+			parts:   parts,
+			fixCase: strings.IndexFunc(string(rest), func(r rune) bool { return unicode.IsUpper(r) || unicode.IsDigit(r) }) >= 0,
+		})
+	}
+	if len(list) == 0 {
+		return 0
+	}
+
+	n := 0
+	for i := range t.Segments {
+		var c int
+		t.Segments[i].Text, c = correctText(t.Segments[i].Text, list)
+		n += c
+	}
+	var c int
+	t.Text, c = correctText(t.Text, list)
+	if len(t.Segments) == 0 {
+		n = c
+	}
+	return n
+}
+
+// correctText 更正 text 中的术语。替换与术语完全相同 (不区分大小写) 的整个单词，
+// 去掉空格后与术语相同的拆开或连写的形式 (Whisper 常把 PyTorch 拆成 Py Torch)，
+// 以及受限的拼写错误 (见 fuzzyMatch)
+func correctText(text string, terms []glossaryTerm) (string, int) {
+	locs := glossaryWord.FindAllStringIndex(text, -1)
+	var b strings.Builder
+	n, last := 0, 0
+	for i := 0; i < len(locs); {
+		term, size := matchTerm(text, locs[i:], terms)
+		if size == 0 {
+			i++
+			continue
+		}
+		start, end := locs[i][0], locs[i+size-1][1]
+		b.WriteString(text[last:start])
+		b.WriteString(term)
+		if text[start:end] != term {
+			n++
+		}
+		last = end
+		i += size
+	}
+	b.WriteString(text[last:])
+	return b.String(), n
+}
+
+// matchTerm 返回从 locs[0] 开始匹配的术语和占用的单词数，没有匹配时 size 为 0；
+// 先找单词数相同的术语，再找多一个或少一个单词的拆开、连写形式，最后找单词数相同的拼写错误
+func matchTerm(text string, locs [][]int, terms []glossaryTerm) (string, int) {
+	window := func(size int) (string, bool) {
+		if size < 1 || size > len(locs) {
+			return "", false
+		}
+		words := make([]string, size)
+		for j := 0; j < size; j++ {
+			// 单词之间只能有空白，不能跨越标点
+			if j > 0 && strings.TrimSpace(text[locs[j-1][1]:locs[j][0]]) != "" {
+				return "", false
+			}
+			words[j] = text[locs[j][0]:locs[j][1]]
+		}
+		return strings.Join(words, " "), true
+	}
+
+	for _, term := range terms {
+		if w, ok := window(term.words); ok && glossaryKey(strings.Fields(w)) == term.key {
+			if term.fixCase {
+				return term.text, term.words
+			}
+			return w, term.words
+		}
+	}
+	for _, term := range terms {
+		for _, size := range []int{term.words + 1, term.words - 1} {
+			if w, ok := window(size); ok && glossaryKey(strings.Fields(w)) == term.key {
+				return term.text, size
+			}
+		}
+	}
+	for _, term := range terms {
+		if w, ok := window(term.words); ok && fuzzyMatch(term, strings.Fields(w)) {
+			return term.text, term.words
+		}
+	}
+	return "", 0
+}
+
+// fuzzyMatch 判断 words 是否为术语的拼写错误 (如 Kubernetis -> Kubernetes)。为避免把普通单词改成术语，
+// 只匹配 5 个字母以上的术语，首字母必须相同，5-8 个字母的术语最多相差 1 处，更长的最多相差 2 处；
+// 与术语不同的单词不能是常用词或术语加减词尾的形式 (如 reach、reacts 不会改成 React)
+func fuzzyMatch(term glossaryTerm, words []string) bool {
+	key := []rune(term.key)
+	if len(key) < 5 {
+		return false
+	}
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if word == term.parts[i] {
+			continue
+		}
+		if len([]rune(word)) < 4 || commonWords[word] || strings.HasPrefix(word, term.parts[i]) || strings.HasPrefix(term.parts[i], word) {
+			return false
+		}
+	}
+	got := []rune(glossaryKey(words))
+	if got[0] != key[0] {
+		return false
+	}
+	limit := 1
+	if len(key) > 8 {
+		limit = 2
+	}
+	return editDistance(got, key, limit) <= limit
+}
+
+// editDistance 返回 a、b 之间的 Damerau-Levenshtein 距离 (相邻字母交换算一处)，
+// 长度之差超过 limit 时直接返回 limit+1
+func editDistance(a, b []rune, limit int) int {
+	if len(a)-len(b) > limit || len(b)-len(a) > limit {
+		return limit + 1
+	}
+	// rows[0..2] 分别为前两行、前一行和当前行
+	rows := [3][]int{make([]int, len(b)+1), make([]int, len(b)+1), make([]int, len(b)+1)}
+	for j := range rows[1] {
+		rows[1][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := rows[2]
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(rows[1][j]+1, cur[j-1]+1, rows[1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], rows[0][j-2]+1)
+			}
+		}
+		rows[0], rows[1], rows[2] = rows[1], cur, rows[0]
+	}
+	return rows[1][len(b)]
+}
+
+// commonWords 为常见的英文单词，拼写相近的术语 (如 Swift、Flask) 不会替换这些单词
+var commonWords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(`
+		about above after again against along among another answer around asked
+		began being below better between black board bring build built called
+		carry cause change check child class clean clear close color could count
+		cover cross field final first flash float floor force found frame front
+		given going great green group heard heavy house human known large later
+		learn least leave level light limit lines local makes might money month
+		never night north number often order other paper parse party people place
+		plain plane plant point power press price print quick quiet raise reach
+		react ready right river round scale serve share shift short should shown
+		since small sound south space speak spell stack stand start state still
+		stone store story study table taken their there these thing think those
+		three times today total touch track trade train tried under until using
+		value voice water where which while white whole words world would write
+		wrong years young
+	`) {
+		words[word] = true
+	}
+	return words
+}()
+
+// glossaryKey 返回用于比较的形式：去掉空格并转为小写
+func glossaryKey(words []string) string {
+	return strings.ToLower(strings.Join(words, ""))
+}
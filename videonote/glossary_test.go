@@ -0,0 +1,84 @@
+package videonote
+
+import "testing"
+
+func TestCorrectGlossary(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms []string
+		text  string
+		want  string
+		n     int
+	}{
+		{
+			name:  "common words unchanged",
+			terms: []string{"React", "Python"},
+			text:  "We want to reach more users and it reacts quickly. Pythons are snakes.",
+			want:  "We want to reach more users and it reacts quickly. Pythons are snakes.",
+		},
+		{
+			name:  "lowercase common word keeps case",
+			terms: []string{"React", "Python"},
+			text:  "react to the python in the zoo",
+			want:  "react to the python in the zoo",
+		},
+		{
+			name:  "case fixed for mixed-case term",
+			terms: []string{"PyTorch", "iOS"},
+			text:  "we train with pytorch on IOS devices",
+			want:  "we train with PyTorch on iOS devices",
+			n:     2,
+		},
+		{
+			name:  "split words merged",
+			terms: []string{"JavaScript", "PyTorch"},
+			text:  "Java Script and Py Torch",
+			want:  "JavaScript and PyTorch",
+			n:     2,
+		},
+		{
+			name:  "merged words split",
+			terms: []string{"Visual Studio"},
+			text:  "open visualstudio now",
+			want:  "open Visual Studio now",
+			n:     1,
+		},
+		{
+			name:  "misspelling fuzzy matched",
+			terms: []string{"Kubernetes", "Postgres"},
+			text:  "deploy Postrges to Kubernetis",
+			want:  "deploy Postgres to Kubernetes",
+			n:     2,
+		},
+		{
+			name:  "near-miss common word unchanged",
+			terms: []string{"Swift", "Flask"},
+			text:  "shift gears in a flash",
+			want:  "shift gears in a flash",
+		},
+		{
+			name:  "different first letter unchanged",
+			terms: []string{"Kubernetes"},
+			text:  "Cubernetes",
+			want:  "Cubernetes",
+		},
+		{
+			name:  "split across punctuation unchanged",
+			terms: []string{"JavaScript"},
+			text:  "Java. Script",
+			want:  "Java. Script",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transcript := &Transcript{Text: tt.text}
+			n := correctGlossary(transcript, tt.terms)
+			if transcript.Text != tt.want {
+				t.Errorf("更正后为 %q，期望 %q", transcript.Text, tt.want)
+			}
+			if n != tt.n {
+				t.Errorf("更正了 %d 处，期望 %d 处", n, tt.n)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package videonote
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// RegisterPostHookFlag 注册 -post-hook，设置后覆盖配置文件中的 post_hook
+func RegisterPostHookFlag(fs *flag.FlagSet, config *Config) {
+	fs.Func("post-hook", "笔记写入后执行的命令，笔记路径作为最后一个参数传入，如 \"./publish.sh --draft\"", func(s string) error {
+		args, err := splitArgs(s, "-post-hook")
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("-post-hook 不能为空")
+		}
+		config.PostHook = args
+		return nil
+	})
+}
+
+// runPostHook 以笔记路径为最后一个参数执行后处理命令，命令的输出写入标准错误，不影响写入标准输出的笔记；
+// 命令还可以从环境变量读取来源、格式等信息。笔记写入标准输出时没有文件路径，跳过
+func runPostHook(ctx context.Context, config *Config, notePath string, opts SummarizeOptions) error {
+	if notePath == StdoutPath {
+		warnf("笔记写入标准输出，不执行后处理命令")
+		return nil
+	}
+
+	args := append(append([]string(nil), config.PostHook[1:]...), notePath)
+	cmd := exec.CommandContext(ctx, config.PostHook[0], args...)
+	cmd.Env = append(os.Environ(),
+		"VIDEO_NOTE_OUTPUT="+notePath,
+		"VIDEO_NOTE_SOURCE="+opts.Source,
+		"VIDEO_NOTE_TITLE="+opts.Title,
+		"VIDEO_NOTE_FORMAT="+string(opts.Format),
+	)
+	if opts.Metadata {
+		cmd.Env = append(cmd.Env, "VIDEO_NOTE_METADATA="+metadataPath(notePath))
+	}
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	infof("正在执行后处理命令: %s", strings.Join(config.PostHook, " "))
+	debugCommand(cmd)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("后处理命令被中断: %w", ctx.Err())
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("后处理命令执行失败 (退出码 %d)，笔记已写入 %s", exitErr.ExitCode(), notePath)
+		}
+		return fmt.Errorf("后处理命令执行失败，笔记已写入 %s: %w", notePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package videonote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MediaInfo 为 ffprobe 读取的媒体文件信息
+type MediaInfo struct {
+	Path       string
+	FormatName string
+	Duration   time.Duration
+	Size       int64
+	BitRate    int64
+	Streams    []StreamInfo
+	Chapters   []Chapter
+}
+
+// StreamInfo 为媒体文件中的一路音频或视频流
+type StreamInfo struct {
+	Index      int
+	Type       string
+	Codec      string
+	Width      int
+	Height     int
+	SampleRate int
+	Channels   int
+	BitRate    int64
+	Language   string
+}
+
+// AudioStreams 返回文件中的音频流
+func (m *MediaInfo) AudioStreams() []StreamInfo {
+	var streams []StreamInfo
+	for _, s := range m.Streams {
+		if s.Type == "audio" {
+			streams = append(streams, s)
+		}
+	}
+	return streams
+}
+
+// checkAudioTrack 检查媒体文件中是否存在第 track 条音轨，不存在时列出可用的音轨
+func checkAudioTrack(ctx context.Context, config *Config, path string, track int) error {
+	info, err := ProbeMedia(ctx, config, path)
+	if err != nil {
+		return err
+	}
+	streams := info.AudioStreams()
+	if track < len(streams) {
+		return nil
+	}
+	if len(streams) == 0 {
+		return markError(ErrMedia, fmt.Errorf("%s 中没有音频流", path))
+	}
+	var lines []string
+	for i, s := range streams {
+		lines = append(lines, fmt.Sprintf("  %d: %s", i, s.describe()))
+	}
+	return fmt.Errorf("音轨 %d 不存在，%s 中可用的音轨:\n%s", track, path, strings.Join(lines, "\n"))
+}
+
+// describe 返回音频流的编码、采样率、声道数、码率和语言
+func (s StreamInfo) describe() string {
+	details := []string{s.Codec}
+	if s.SampleRate > 0 {
+		details = append(details, fmt.Sprintf("%d Hz", s.SampleRate))
+	}
+	if s.Channels > 0 {
+		details = append(details, fmt.Sprintf("%d 声道", s.Channels))
+	}
+	if s.BitRate > 0 {
+		details = append(details, fmt.Sprintf("%d kb/s", s.BitRate/1000))
+	}
+	if s.Language != "" {
+		details = append(details, s.Language)
+	}
+	return strings.Join(details, ", ")
+}
+
+// ProbeMedia 用 ffprobe 读取媒体文件的格式、流和章节信息
+func ProbeMedia(ctx context.Context, config *Config, path string) (*MediaInfo, error) {
+	cmd := exec.CommandContext(ctx, config.ffprobeBinary(), "-v", "error", "-print_format", "json",
+		"-show_format", "-show_streams", "-show_chapters", path)
+	debugCommand(cmd)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, ffmpegError("ffprobe", path, err, nil)
+	}
+	return parseMediaInfo(path, output)
+}
+
+func parseMediaInfo(path string, data []byte) (*MediaInfo, error) {
+	var result struct {
+		Format struct {
+			FormatName string `json:"format_name"`
+			Duration   string `json:"duration"`
+			Size       string `json:"size"`
+			BitRate    string `json:"bit_rate"`
+		} `json:"format"`
+		Streams []struct {
+			Index      int               `json:"index"`
+			CodecType  string            `json:"codec_type"`
+			CodecName  string            `json:"codec_name"`
+			Width      int               `json:"width"`
+			Height     int               `json:"height"`
+			SampleRate string            `json:"sample_rate"`
+			Channels   int               `json:"channels"`
+			BitRate    string            `json:"bit_rate"`
+			Tags       map[string]string `json:"tags"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析ffprobe输出失败: %w", err)
+	}
+
+	// ffprobe 对缺失的数值字段输出 "N/A" 或直接省略，解析失败时按 0 处理
+	seconds, _ := strconv.ParseFloat(result.Format.Duration, 64)
+	info := &MediaInfo{
+		Path:       path,
+		FormatName: result.Format.FormatName,
+		Duration:   secondsToDuration(seconds),
+	}
+	info.Size, _ = strconv.ParseInt(result.Format.Size, 10, 64)
+	info.BitRate, _ = strconv.ParseInt(result.Format.BitRate, 10, 64)
+
+	for _, s := range result.Streams {
+		stream := StreamInfo{
+			Index:    s.Index,
+			Type:     s.CodecType,
+			Codec:    s.CodecName,
+			Width:    s.Width,
+			Height:   s.Height,
+			Channels: s.Channels,
+			Language: s.Tags["language"],
+		}
+		stream.SampleRate, _ = strconv.Atoi(s.SampleRate)
+		stream.BitRate, _ = strconv.ParseInt(s.BitRate, 10, 64)
+		info.Streams = append(info.Streams, stream)
+	}
+
+	chapters, err := parseChapters(data)
+	if err != nil {
+		return nil, err
+	}
+	info.Chapters = chapters
+	return info, nil
+}
+
+// Print 输出便于阅读的媒体信息，以及按时长估算的转录长度和文本块数
+func (m *MediaInfo) Print(w io.Writer, config *Config) {
+	fmt.Fprintf(w, "文件:       %s (%.1f MB)\n", m.Path, float64(m.Size)/(1<<20))
+	fmt.Fprintf(w, "格式:       %s\n", m.FormatName)
+	fmt.Fprintf(w, "时长:       %s\n", formatTimestamp(m.Duration))
+	if m.BitRate > 0 {
+		fmt.Fprintf(w, "码率:       %d kb/s\n", m.BitRate/1000)
+	}
+
+	// 音轨序号只计音频流，与 generate -audio-track 对应
+	track := 0
+	for _, s := range m.Streams {
+		switch s.Type {
+		case "video":
+			fmt.Fprintf(w, "视频流 #%d:  %s %dx%d\n", s.Index, s.Codec, s.Width, s.Height)
+		case "audio":
+			fmt.Fprintf(w, "音频流 #%d:  %s (音轨 %d)\n", s.Index, s.describe(), track)
+			track++
+		}
+	}
+
+	if len(m.Chapters) > 0 {
+		fmt.Fprintf(w, "章节:       %d 个\n", len(m.Chapters))
+		for _, c := range m.Chapters {
+			fmt.Fprintf(w, "  [%s] %s\n", formatTimestamp(c.Start), c.Title)
+		}
+	}
+
+	tokens := int(m.Duration.Minutes() * estimatedTokensPerMinute)
+	chunks := int(math.Ceil(float64(tokens) / float64(config.chunkTokens())))
+	fmt.Fprintf(w, "预计转录:   ~%d tokens，约 %d 个文本块 (每块 %d tokens)\n", tokens, chunks, config.chunkTokens())
+}
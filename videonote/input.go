@@ -0,0 +1,107 @@
+package videonote
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 识别为音频的文件扩展名
+var audioExtensions = map[string]bool{
+	".mp3":  true,
+	".m4a":  true,
+	".wav":  true,
+	".aac":  true,
+	".flac": true,
+	".ogg":  true,
+	".oga":  true,
+	".opus": true,
+	".wma":  true,
+	".mpga": true,
+}
+
+// 转录接口可以直接接收的音频扩展名，其余音频格式需要先用 ffmpeg 转码
+var transcribableExtensions = map[string]bool{
+	".mp3":  true,
+	".m4a":  true,
+	".wav":  true,
+	".flac": true,
+	".ogg":  true,
+	".oga":  true,
+	".mpga": true,
+}
+
+// 可直接作为转录使用的字幕文件扩展名
+var subtitleExtensions = map[string]bool{
+	".srt": true,
+	".vtt": true,
+}
+
+func fileExt(path string) string {
+	return strings.ToLower(filepath.Ext(path))
+}
+
+func isVideoFile(path string) bool {
+	return videoExtensions[fileExt(path)]
+}
+
+func isAudioFile(path string) bool {
+	return audioExtensions[fileExt(path)]
+}
+
+// IsSubtitleFile 判断输入是否为字幕文件，字幕跳过提取音频和转录，不需要 ffmpeg
+func IsSubtitleFile(path string) bool {
+	return subtitleExtensions[fileExt(path)]
+}
+
+// describeExt 返回用于错误提示的扩展名描述
+func describeExt(path string) string {
+	if ext := fileExt(path); ext != "" {
+		return ext
+	}
+	return "无扩展名的文件"
+}
+
+// CheckMediaInput 检查 generate 的输入是否为已知的视频、音频或字幕文件，在线链接不检查
+func CheckMediaInput(path string) error {
+	if isURL(path) || isVideoFile(path) || isAudioFile(path) || IsSubtitleFile(path) {
+		return nil
+	}
+	if ext := fileExt(path); ext == ".txt" || ext == ".md" {
+		return fmt.Errorf("generate 需要视频或音频文件，但输入为 %s —— 是否要使用 summarize？", ext)
+	}
+	return fmt.Errorf("generate 不支持 %s: %s (支持的格式: %s)",
+		describeExt(path), path, joinExts(videoExtensions, audioExtensions, subtitleExtensions))
+}
+
+// CheckAudioInput 检查 transcribe 的输入是否为已知的音频文件
+func CheckAudioInput(path string) error {
+	if isAudioFile(path) {
+		return nil
+	}
+	if isVideoFile(path) {
+		return fmt.Errorf("transcribe 需要音频文件，但输入为 %s —— 是否要使用 generate？", fileExt(path))
+	}
+	return fmt.Errorf("transcribe 不支持 %s: %s (支持的格式: %s)",
+		describeExt(path), path, joinExts(audioExtensions))
+}
+
+// CheckTextInput 检查 summarize 的输入不是音视频文件
+func CheckTextInput(path string) error {
+	if isVideoFile(path) || isAudioFile(path) {
+		return fmt.Errorf("summarize 需要转录文本文件，但输入为 %s —— 是否要使用 generate？", fileExt(path))
+	}
+	return nil
+}
+
+func joinExts(sets ...map[string]bool) string {
+	var exts []string
+	for _, set := range sets {
+		for ext := range set {
+			exts = append(exts, ext)
+		}
+	}
+	sort.Strings(exts)
+	return strings.Join(exts, ", ")
+}
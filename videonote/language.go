@@ -0,0 +1,96 @@
+package videonote
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 常用语言代码对应的语言名称，用于在提示词中指定摘要语言
+var languageNames = map[string]string{
+	"zh": "简体中文",
+	"en": "英文 (English)",
+	"ja": "日文 (日本語)",
+	"ko": "韩文 (한국어)",
+	"fr": "法文 (Français)",
+	"de": "德文 (Deutsch)",
+	"es": "西班牙文 (Español)",
+	"ru": "俄文 (Русский)",
+}
+
+// 英文提示词中使用的语言名称
+var englishLanguageNames = map[string]string{
+	"zh": "Simplified Chinese",
+	"en": "English",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"fr": "French",
+	"de": "German",
+	"es": "Spanish",
+	"ru": "Russian",
+}
+
+// Whisper 返回的语言名称对应的语言代码
+var whisperLanguageCodes = map[string]string{
+	"chinese":  "zh",
+	"english":  "en",
+	"japanese": "ja",
+	"korean":   "ko",
+	"french":   "fr",
+	"german":   "de",
+	"spanish":  "es",
+	"russian":  "ru",
+}
+
+// detectLanguage 按文字类型粗略判断文本的主要语言：中日韩文字占多数时返回对应语言，
+// 拉丁字母占多数时视为英文，无法判断时返回空字符串
+func detectLanguage(text string) string {
+	var han, kana, hangul, latin int
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case r < utf8.RuneSelf && unicode.IsLetter(r):
+			latin++
+		}
+	}
+
+	// 中日韩文字一个字约等于几个拉丁字母，按 1:3 比较
+	cjk := han + kana + hangul
+	switch {
+	case cjk == 0 && latin == 0:
+		return ""
+	case cjk*3 < latin:
+		return "en"
+	case hangul > han+kana:
+		return "ko"
+	case kana > 0 && kana*5 >= cjk:
+		return "ja"
+	default:
+		return "zh"
+	}
+}
+
+// ValidateSourceLanguage 检查 -source-lang 是否为两个字母的 ISO-639-1 语言代码
+func ValidateSourceLanguage(code string) error {
+	if code == "" {
+		return nil
+	}
+	if len(code) != 2 || !isLetters(code) {
+		return fmt.Errorf("无效的语言代码 %q，请使用两个小写字母的 ISO-639-1 代码，如 zh、en", code)
+	}
+	return nil
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package videonote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// logLevel 为当前的日志级别，由 -log-level 设置
+var logLevel = new(slog.LevelVar)
+
+// jsonLogs 为 true 时日志以 JSON 逐行输出，此时不显示原地刷新的进度条
+var jsonLogs bool
+
+// colorOutput 为 true 时日志和进度条使用 ANSI 颜色
+var colorOutput bool
+
+// ANSI 颜色代码
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+// SetupLogging 按 -log-level 和 -json-logs 配置全局日志，日志统一输出到标准错误；
+// 标准错误为终端时使用颜色，noColor 或设置了 NO_COLOR 环境变量时不使用
+func SetupLogging(level string, json, noColor bool) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("无效的日志级别 %q (可选: debug, info, warn, error)", level)
+	}
+	logLevel.Set(l)
+	jsonLogs = json
+	colorOutput = !json && !noColor && useColor(os.Stderr)
+
+	opts := &slog.HandlerOptions{Level: logLevel}
+	var handler slog.Handler
+	if json {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = &plainHandler{w: os.Stderr, level: logLevel, mu: &sync.Mutex{}}
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+func logf(level slog.Level, format string, args ...any) {
+	ctx := context.Background()
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+	slog.Default().Log(ctx, level, fmt.Sprintf(format, args...))
+}
+
+// debugf 记录内部细节，如执行的外部命令和接口耗时
+func debugf(format string, args ...any) { logf(slog.LevelDebug, format, args...) }
+
+// infof 记录面向用户的处理进度
+func infof(format string, args ...any) { logf(slog.LevelInfo, format, args...) }
+
+func warnf(format string, args ...any) { logf(slog.LevelWarn, format, args...) }
+
+func errorf(format string, args ...any) { logf(slog.LevelError, format, args...) }
+
+// useColor 判断是否在 f 上使用颜色：按 https://no-color.org 的约定，NO_COLOR 非空时不使用；
+// 重定向到文件、管道或 TERM=dumb 时输出纯文本
+func useColor(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return IsTerminal(f)
+}
+
+// colorize 在启用颜色时用 code 包裹 s
+func colorize(s, code string) string {
+	if !colorOutput {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// plainHandler 以 "时间 [级别] 消息" 的形式输出便于阅读的日志，info 级别不显示级别标记；
+// 启用颜色时时间变暗，错误和警告分别以红色和黄色显示
+type plainHandler struct {
+	w     io.Writer
+	level slog.Leveler
+	attrs []slog.Attr
+	mu    *sync.Mutex
+}
+
+func (h *plainHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *plainHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(colorize(r.Time.Format("2006/01/02 15:04:05"), ansiDim))
+	var label, code string
+	switch {
+	case r.Level >= slog.LevelError:
+		label, code = " [错误]", ansiBold+ansiRed
+	case r.Level >= slog.LevelWarn:
+		label, code = " [警告]", ansiYellow
+	case r.Level < slog.LevelInfo:
+		label, code = " [调试]", ansiDim
+	}
+	if code != "" {
+		label, r.Message = colorize(label, code), colorize(r.Message, code)
+	}
+	b.WriteString(label + " " + r.Message)
+
+	writeAttr := func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		return true
+	}
+	for _, a := range h.attrs {
+		writeAttr(a)
+	}
+	r.Attrs(writeAttr)
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *plainHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &clone
+}
+
+func (h *plainHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// debugCommand 在 debug 级别记录即将执行的外部命令
+func debugCommand(cmd *exec.Cmd) {
+	debugf("执行命令: %s", strings.Join(cmd.Args, " "))
+}
@@ -0,0 +1,105 @@
+package videonote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetadataVersion 为 .meta.json 的格式版本，字段含义或类型发生不兼容的变化时递增；
+// 只新增字段时保持不变
+const MetadataVersion = 1
+
+// Metadata 为 -metadata 在笔记旁写入的 .meta.json，字段名保持稳定供归档和检索工具解析
+type Metadata struct {
+	SchemaVersion int    `json:"schema_version"`
+	Source        string `json:"source"`
+	Note          string `json:"note"`
+	// DurationSeconds 为视频或音频的时长，无法获取时省略
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	Model           string  `json:"model"`
+	// Language 为转录识别出的语言代码，无法判断时省略
+	Language string `json:"language,omitempty"`
+	Chunks   int    `json:"chunks"`
+	// PromptTokens 和 CompletionTokens 为生成这份笔记的摘要请求用量，流式生成时接口不返回用量
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	GeneratedAt      time.Time `json:"generated_at"`
+}
+
+// metadataPath 返回笔记对应的 .meta.json 路径
+func metadataPath(notePath string) string {
+	return strings.TrimSuffix(notePath, filepath.Ext(notePath)) + ".meta.json"
+}
+
+// writeNoteMetadata 在笔记旁写入元数据；笔记写入标准输出时没有对应的文件路径，跳过
+func writeNoteMetadata(notePath string, transcript *Transcript, model string, chunks int, u *usage, opts SummarizeOptions) error {
+	if notePath == StdoutPath {
+		warnf("笔记写入标准输出，不生成元数据文件")
+		return nil
+	}
+	duration := opts.Duration
+	if n := len(transcript.Segments); duration == 0 && n > 0 {
+		duration = transcript.Segments[n-1].End
+	}
+	promptTokens, completionTokens := u.tokens()
+	return writeMetadata(metadataPath(notePath), Metadata{
+		SchemaVersion:    MetadataVersion,
+		Source:           opts.Source,
+		Note:             notePath,
+		DurationSeconds:  duration.Seconds(),
+		Model:            model,
+		Language:         transcript.language(),
+		Chunks:           chunks,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		GeneratedAt:      time.Now().UTC().Truncate(time.Second),
+	})
+}
+
+func writeMetadata(path string, meta Metadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("编码元数据失败: %w", err)
+	}
+	if err := writeOutput(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("写入元数据失败: %w", err)
+	}
+	return nil
+}
+
+// usage 累计单份笔记的 token 用量；批量处理时多份笔记并发生成，全局的 metrics 无法区分
+type usage struct {
+	mu               sync.Mutex
+	promptTokens     int
+	completionTokens int
+}
+
+type usageKey struct{}
+
+// withUsage 返回会累计 token 用量的 context
+func withUsage(ctx context.Context) (context.Context, *usage) {
+	u := &usage{}
+	return context.WithValue(ctx, usageKey{}, u), u
+}
+
+// addUsage 将一次请求的用量计入全局统计，以及 ctx 中的 usage (如果有)
+func addUsage(ctx context.Context, prompt, completion int) {
+	metrics.addTokens(prompt, completion)
+	if u, ok := ctx.Value(usageKey{}).(*usage); ok {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		u.promptTokens += prompt
+		u.completionTokens += completion
+	}
+}
+
+func (u *usage) tokens() (prompt, completion int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.promptTokens, u.completionTokens
+}
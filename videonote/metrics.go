@@ -0,0 +1,133 @@
+package videonote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// runMetrics 累计一次运行中处理的文件、音频时长、接口调用和 token 用量，
+// 用于批量处理结束时的报告和 -report
+type runMetrics struct {
+	mu               sync.Mutex
+	start            time.Time
+	files            int
+	failed           int
+	audio            time.Duration
+	apiCalls         int
+	retries          int
+	promptTokens     int
+	completionTokens int
+	notes            []string
+}
+
+// metrics 为全局的运行统计，可在多个 goroutine 中并发更新
+var metrics = &runMetrics{start: time.Now()}
+
+// fileDone 记录一个输入处理完成，err 不为 nil 时计为失败
+func (m *runMetrics) fileDone(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files++
+	if err != nil {
+		m.failed++
+	}
+}
+
+func (m *runMetrics) addAudio(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.audio += d
+}
+
+// addCall 记录一次接口调用，retry 为 true 表示这是重试
+func (m *runMetrics) addCall(retry bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apiCalls++
+	if retry {
+		m.retries++
+	}
+}
+
+func (m *runMetrics) addTokens(prompt, completion int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.promptTokens += prompt
+	m.completionTokens += completion
+}
+
+// addNote 记录写入的笔记文件
+func (m *runMetrics) addNote(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notes = append(m.notes, path)
+}
+
+// WrittenNotes 返回当前进程写入的笔记路径，按写入顺序排列
+func WrittenNotes() []string {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	return append([]string(nil), metrics.notes...)
+}
+
+// FileDone 记录一个输入处理完成，供调用方在 Generate 等单文件流程之后统计
+func FileDone(err error) { metrics.fileDone(err) }
+
+// RunReport 返回当前进程累计的运行报告
+func RunReport() Report { return metrics.report() }
+
+// Report 为 -report 写入的运行报告，字段名保持稳定供下游工具解析
+type Report struct {
+	Files            int     `json:"files"`
+	Failed           int     `json:"failed"`
+	AudioSeconds     float64 `json:"audio_seconds"`
+	APICalls         int     `json:"api_calls"`
+	Retries          int     `json:"retries"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	ElapsedSeconds   float64 `json:"elapsed_seconds"`
+}
+
+func (m *runMetrics) report() Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Report{
+		Files:            m.files,
+		Failed:           m.failed,
+		AudioSeconds:     m.audio.Seconds(),
+		APICalls:         m.apiCalls,
+		Retries:          m.retries,
+		PromptTokens:     m.promptTokens,
+		CompletionTokens: m.completionTokens,
+		ElapsedSeconds:   time.Since(m.start).Round(time.Millisecond).Seconds(),
+	}
+}
+
+// Print 输出便于阅读的运行报告；流式生成时接口不返回用量，token 数可能偏少
+func (r Report) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "运行报告:")
+	fmt.Fprintf(tw, "  文件:\t%d (失败 %d)\n", r.Files, r.Failed)
+	fmt.Fprintf(tw, "  音频时长:\t%s\n", formatTimestamp(secondsToDuration(r.AudioSeconds)))
+	fmt.Fprintf(tw, "  接口调用:\t%d (重试 %d)\n", r.APICalls, r.Retries)
+	fmt.Fprintf(tw, "  输入tokens:\t%d\n", r.PromptTokens)
+	fmt.Fprintf(tw, "  输出tokens:\t%d\n", r.CompletionTokens)
+	fmt.Fprintf(tw, "  总耗时:\t%s\n", secondsToDuration(r.ElapsedSeconds).Round(time.Second))
+	tw.Flush()
+}
+
+// WriteReport 将运行报告以 JSON 写入 path
+func WriteReport(path string, r Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("编码运行报告失败: %w", err)
+	}
+	if err := writeOutput(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("写入运行报告失败: %w", err)
+	}
+	return nil
+}
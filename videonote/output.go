@@ -0,0 +1,123 @@
+package videonote
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// StdoutPath 作为输出路径时表示写入标准输出
+const StdoutPath = "-"
+
+// StdinPath 作为输入路径时表示从标准输入读取
+const StdinPath = "-"
+
+// utf8BOM 为 UTF-8 的字节顺序标记，部分 Windows 编辑器依靠它识别编码
+const utf8BOM = "\uFEFF"
+
+// writeOutput 将结果写入 path，path 为 "-" 时写入标准输出以便在管道中使用；
+// 模型或转录偶尔返回截断的多字节字符，写入前替换为 U+FFFD，保证输出总是有效的 UTF-8
+func writeOutput(path string, data []byte) error {
+	if !utf8.Valid(data) {
+		warnf("输出中包含无效的 UTF-8 字符，已替换为 U+FFFD")
+		data = bytes.ToValidUTF8(data, []byte("\uFFFD"))
+	}
+	if path == StdoutPath {
+		if _, err := os.Stdout.Write(data); err != nil {
+			return fmt.Errorf("写入标准输出失败: %w", err)
+		}
+		return nil
+	}
+	return writeFile(path, string(data))
+}
+
+// writeFile 写入文件，父目录不存在时自动创建；先写入同目录下的临时文件并同步到磁盘，
+// 再重命名为 path，异常退出时不会留下不完整的文件，也不会破坏已有的输出
+func writeFile(path, data string) (err error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建输出目录失败: %w", err)
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建输出文件失败: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	n, err := io.WriteString(f, data)
+	if err == nil && n < len(data) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("同步 %s 到磁盘失败: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("关闭输出文件失败: %w", err)
+	}
+	// CreateTemp 创建的文件权限为 0600，改为与 os.Create 一致
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return fmt.Errorf("设置 %s 权限失败: %w", path, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+// Overwrite 决定输出文件已存在时的处理方式
+type Overwrite int
+
+const (
+	// OverwriteRefuse 拒绝覆盖并报错，避免误删手动修改过的笔记
+	OverwriteRefuse Overwrite = iota
+	// OverwriteReplace 直接覆盖已有文件
+	OverwriteReplace
+	// OverwriteSkip 跳过已有输出的文件，适合批量处理时补全缺失的笔记
+	OverwriteSkip
+)
+
+// OverwritePolicy 根据 -overwrite 和 -no-clobber 确定处理方式
+func OverwritePolicy(overwrite, noClobber bool) (Overwrite, error) {
+	switch {
+	case overwrite && noClobber:
+		return 0, fmt.Errorf("-overwrite 和 -no-clobber 不能同时使用")
+	case overwrite:
+		return OverwriteReplace, nil
+	case noClobber:
+		return OverwriteSkip, nil
+	default:
+		return OverwriteRefuse, nil
+	}
+}
+
+// CheckOutput 按策略检查输出文件，返回 true 表示应跳过本次处理
+func CheckOutput(path string, policy Overwrite) (bool, error) {
+	if path == StdoutPath || policy == OverwriteReplace {
+		return false, nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("检查输出文件失败: %w", err)
+	}
+	if policy == OverwriteSkip {
+		infof("输出文件已存在，跳过: %s", path)
+		return true, nil
+	}
+	return false, fmt.Errorf("输出文件已存在: %s (使用 -overwrite 覆盖，或 -no-clobber 跳过)", path)
+}
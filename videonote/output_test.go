@@ -0,0 +1,81 @@
+package videonote
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteOutputCreatesParentDirs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a", "b", "notes.txt")
+	if err := writeOutput(path, []byte("笔记内容")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "笔记内容" {
+		t.Errorf("写入的内容为 %q", data)
+	}
+	// 不应留下临时文件
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("输出目录中有 %d 个文件，期望只有笔记", len(entries))
+	}
+}
+
+func TestWriteOutputUnwritable(t *testing.T) {
+	t.Run("parent is a file", func(t *testing.T) {
+		file := writeTestFile(t, "notes", "")
+		err := writeOutput(filepath.Join(file, "notes.txt"), []byte("笔记内容"))
+		if err == nil || !strings.Contains(err.Error(), "创建输出目录失败") {
+			t.Errorf("err = %v，期望说明无法创建输出目录", err)
+		}
+	})
+	t.Run("permission denied", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root 不受目录权限限制")
+		}
+		dir := t.TempDir()
+		if err := os.Chmod(dir, 0555); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Chmod(dir, 0755) })
+		err := writeOutput(filepath.Join(dir, "notes.txt"), []byte("笔记内容"))
+		if !errors.Is(err, fs.ErrPermission) || !strings.Contains(err.Error(), "创建输出文件失败") {
+			t.Errorf("err = %v，期望说明没有写入权限", err)
+		}
+	})
+}
+
+// 转录结果写入不存在的目录时自动创建
+func TestTranscribeMissingOutputDir(t *testing.T) {
+	api := newFakeAPI(t)
+	input := writeTestFile(t, "talk.mp3", "fake audio")
+	output := filepath.Join(t.TempDir(), "out", "talk.txt")
+	_, err := Transcribe(context.Background(), api.config(t), input, output, TranscribeOptions{
+		Format:      FormatText,
+		SegmentTime: 10 * time.Minute,
+		Quiet:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), api.Transcript) {
+		t.Errorf("转录为 %q", data)
+	}
+}
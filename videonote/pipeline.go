@@ -0,0 +1,1186 @@
+package videonote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// GenerateOptions 控制从视频生成笔记的完整流程
+type GenerateOptions struct {
+	Ratio       float64
+	Format      Format
+	Mode        Mode
+	Prompt      *template.Template
+	Concurrency int
+	Language    string
+	SegmentTime time.Duration
+	Timestamps  bool
+	Translate   bool
+	CookiesPath string
+	Quiet       bool
+	// DryRun 为 true 时只按音频时长预估用量与费用，不调用API
+	DryRun bool
+	// DryRunOutput 为 DryRun 时写入输入路径和预估结果的位置，为 nil 时不输出
+	DryRunOutput io.Writer
+	// KeepFiles 为 true 时将音频和原始转录保存在笔记旁边
+	KeepFiles bool
+	// WorkDir 不为空时将音频和原始转录保存在该目录
+	WorkDir string
+	// Diarize 为 true 时标注说话人
+	Diarize bool
+	Stream  bool
+	Extract ExtractOptions
+	// CacheDir 不为空时缓存转录结果
+	CacheDir string
+	Stats    bool
+	// SourceLanguage 和 TranscribePrompt 见 TranscribeOptions
+	SourceLanguage   string
+	TranscribePrompt string
+	// Transcriber 为转录后端，见 TranscribeOptions.Backend
+	Transcriber string
+	// TranscribeEngine 见 TranscribeOptions.Engine，SummarizeEngine 见 SummarizeOptions.Engine
+	TranscribeEngine Transcriber
+	SummarizeEngine  Summarizer
+	// WhisperFormat 和 WhisperTemperature 见 TranscribeOptions 的 ResponseFormat 和 Temperature
+	WhisperFormat      string
+	WhisperTemperature float32
+	// Overwrite 为笔记文件已存在时的处理方式
+	Overwrite Overwrite
+	// IncludeTranscript 为 true 时将完整转录附在笔记之后
+	IncludeTranscript bool
+	// ChunkOverlap、CleanTranscript 和 Dedup 见 SummarizeOptions
+	ChunkOverlap    int
+	CleanTranscript bool
+	Dedup           bool
+	// BOM 为 true 时在笔记开头写入 UTF-8 BOM，见 SummarizeOptions
+	BOM bool
+	// TOC 为笔记开头的目录样式，设置后自动启用 Timestamps
+	TOC TOC
+	// Refine 和 RatioTolerance 见 SummarizeOptions
+	Refine         int
+	RatioTolerance float64
+	// Words 为最终笔记的目标字数，见 SummarizeOptions
+	Words int
+	// Extractors 见 SummarizeOptions
+	Extractors []Extractor
+	// Metadata 为 true 时在笔记旁写入 .meta.json，见 SummarizeOptions
+	Metadata bool
+	// MinLength 见 SummarizeOptions
+	MinLength int
+	// ConfidenceThreshold 见 TranscribeOptions
+	ConfidenceThreshold float64
+	// FailFast 为 true 时遇到第一个错误就停止，见 SummarizeOptions；
+	// 批量处理时还会停止启动新的文件
+	FailFast bool
+	// Outputs 为本次运行写入的输出，为空时只生成笔记；转录和字幕按 OutputTemplate 命名写在笔记旁边
+	Outputs        []Artifact
+	OutputTemplate string
+	// Glossary 为术语表，见 TranscribeOptions 和 SummarizeOptions
+	Glossary []string
+	// Preset 见 SummarizeOptions
+	Preset string
+	// Pipeline 为 true 时每段音频转录完成后立即开始摘要，并将已完成的部分逐步写入笔记；
+	// 不能与 Diarize 和 Words 同时使用，忽略 MinLength
+	Pipeline bool
+}
+
+// Generate 对单个视频执行 提取音频 -> 转录 -> 摘要 的完整流程，返回笔记文件路径
+func Generate(ctx context.Context, config *Config, videoPath, outputPath string, opts GenerateOptions) (string, error) {
+	// 临时文件
+	tmpDir, err := os.MkdirTemp("", "video-note-")
+	if err != nil {
+		return "", fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	source := videoPath
+	// 在线视频先下载到临时目录，笔记默认写入当前目录
+	if isURL(videoPath) {
+		infof("正在下载视频: %s", videoPath)
+		videoPath, err = downloadMedia(ctx, videoPath, tmpDir, opts.CookiesPath)
+		if err != nil {
+			return "", fmt.Errorf("下载视频失败: %w", err)
+		}
+		if outputPath == "" {
+			outputPath = strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath)) + opts.Format.Ext()
+		}
+	}
+
+	ext := filepath.Ext(videoPath)
+	if outputPath == "" {
+		outputPath = defaultNotePath(videoPath, opts.Format)
+	}
+	if len(opts.Outputs) == 0 {
+		opts.Outputs = []Artifact{ArtifactSummary}
+	}
+	artifacts := artifactPaths(opts.Outputs, outputPath, videoPath, opts.OutputTemplate)
+	if !opts.DryRun {
+		if opts.Outputs, err = checkOutputs(opts.Outputs, outputPath, artifacts, opts.Overwrite); err != nil {
+			return "", err
+		}
+		if len(opts.Outputs) == 0 {
+			return outputPath, nil
+		}
+	}
+
+	// 已有字幕时跳过提取音频和转录
+	if IsSubtitleFile(videoPath) {
+		return generateFromSubtitles(ctx, config, videoPath, outputPath, artifacts, opts)
+	}
+	if err := checkSubtitleModel(config, opts); err != nil {
+		return "", err
+	}
+
+	audioExt := opts.Extract.audioExt()
+	audioPath := filepath.Join(tmpDir, "audio"+audioExt)
+	transcriptPath := filepath.Join(tmpDir, "transcript.txt")
+	switch {
+	case opts.KeepFiles:
+		base := strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+		if outputPath == StdoutPath {
+			base = strings.TrimSuffix(filepath.Base(videoPath), ext)
+		}
+		audioPath = base + ".audio" + audioExt
+		transcriptPath = base + ".transcript.txt"
+	case opts.WorkDir != "":
+		if err := os.MkdirAll(opts.WorkDir, 0755); err != nil {
+			return "", fmt.Errorf("创建工作目录失败: %w", err)
+		}
+		name := strings.TrimSuffix(filepath.Base(videoPath), ext)
+		audioPath = filepath.Join(opts.WorkDir, name+audioExt)
+		transcriptPath = filepath.Join(opts.WorkDir, name+".transcript.txt")
+	}
+
+	// 1. 提取音频；输入已是可以直接转录的音频时跳过
+	var tm *timeMap
+	if isAudioFile(videoPath) && useAudioDirectly(videoPath, opts.Extract) {
+		infof("输入已是音频文件，跳过提取，直接转录")
+		audioPath = videoPath
+	} else {
+		infof("正在从视频中提取音频...")
+		if tm, err = extractAudio(ctx, config, videoPath, audioPath, opts.Extract); err != nil {
+			return "", fmt.Errorf("提取音频失败: %w", err)
+		}
+		if opts.Extract.codec().lossless && opts.Transcriber != transcriberLocal && opts.TranscribeEngine == nil {
+			warnLosslessSize(audioPath)
+		}
+	}
+
+	if opts.DryRun {
+		duration, err := probeDuration(ctx, config, audioPath)
+		if err != nil {
+			return "", fmt.Errorf("获取音频时长失败: %w", err)
+		}
+		est := estimateFromDuration(config, duration, opts.Ratio)
+		if opts.Transcriber == transcriberLocal {
+			est.TranscribeModel = "whisper.cpp"
+			est.TranscribeCost, est.TranscribeKnown = 0, true
+		}
+		return "", opts.printEstimate(videoPath, est)
+	}
+
+	// 视频带有章节时按章节生成笔记，需要片段级时间戳来划分章节
+	chapters, err := probeChapters(ctx, config, videoPath)
+	if err != nil {
+		warnf("读取章节信息失败，按固定长度分块: %v", err)
+	} else if chapters = clipChapters(chapters, opts.Extract.Start, opts.Extract.End); len(chapters) > 0 {
+		infof("检测到%d个章节，将按章节生成笔记", len(chapters))
+	}
+
+	summarizeOpts := opts.summarizeOptions(strings.TrimSuffix(filepath.Base(videoPath), ext), source)
+	if opts.Metadata && hasArtifact(opts.Outputs, ArtifactSummary) {
+		// 时长按原视频计算，没有 ffprobe 时省略
+		if duration, err := probeDuration(ctx, config, videoPath); err == nil {
+			summarizeOpts.Duration = duration
+		}
+	}
+	// -pipeline 时摘要与转录同时进行，转录完成的每一段都立即开始摘要
+	var pipe *notePipeline
+	var onPart func(part *Transcript)
+	if opts.Pipeline && hasArtifact(opts.Outputs, ArtifactSummary) {
+		pipe = newNotePipeline(ctx, config, outputPath, summarizeOpts)
+		defer pipe.abort()
+		onPart = func(part *Transcript) {
+			tm.apply(part.Segments)
+			part.Chapters = chapters
+			pipe.add(part)
+		}
+	}
+
+	// 2. 音频转文字
+	infof("正在将音频转换为文字...")
+	transcript, err := Transcribe(ctx, config, audioPath, transcriptPath, TranscribeOptions{
+		SegmentTime:         opts.SegmentTime,
+		Timestamps:          opts.Timestamps || opts.TOC != TOCNone || len(chapters) > 0 || needsSubtitles(opts.Outputs),
+		Translate:           opts.Translate,
+		Quiet:               opts.Quiet,
+		Diarize:             opts.Diarize,
+		CacheDir:            opts.CacheDir,
+		Language:            opts.SourceLanguage,
+		Prompt:              opts.TranscribePrompt,
+		Backend:             opts.Transcriber,
+		Engine:              opts.TranscribeEngine,
+		ResponseFormat:      opts.WhisperFormat,
+		Temperature:         opts.WhisperTemperature,
+		Concurrency:         opts.Concurrency,
+		ConfidenceThreshold: opts.ConfidenceThreshold,
+		Glossary:            opts.Glossary,
+		timeMap:             tm,
+		onPart:              onPart,
+	})
+	if err != nil {
+		return "", fmt.Errorf("音频转文字失败: %w", err)
+	}
+	// 去除静音或截取时间段后的时间戳需要换算回原视频时间
+	tm.apply(transcript.Segments)
+	transcript.Chapters = chapters
+	if err := writeArtifacts(transcript, artifacts); err != nil {
+		return "", err
+	}
+
+	// 3. 生成摘要
+	switch {
+	case pipe != nil:
+		infof("正在等待剩余部分的摘要并整合笔记...")
+		if err := pipe.finish(transcript); err != nil {
+			return "", fmt.Errorf("生成摘要失败: %w", err)
+		}
+	case hasArtifact(opts.Outputs, ArtifactSummary):
+		infof("正在生成笔记摘要...")
+		if err := summarizeTranscript(ctx, config, transcript, outputPath, summarizeOpts); err != nil {
+			return "", fmt.Errorf("生成摘要失败: %w", err)
+		}
+	}
+
+	if opts.KeepFiles || opts.WorkDir != "" {
+		if audioPath != videoPath {
+			infof("音频已保存: %s", audioPath)
+		}
+		infof("原始转录已保存: %s", transcriptPath)
+		// 原始转录保持不变，清理后的文本另存一份
+		if opts.CleanTranscript {
+			cleanPath := strings.TrimSuffix(transcriptPath, ".txt") + ".clean.txt"
+			if err := writeOutput(cleanPath, []byte(transcript.Text)); err != nil {
+				return "", fmt.Errorf("写入清理后的转录失败: %w", err)
+			}
+			infof("清理后的转录已保存: %s", cleanPath)
+		}
+	}
+
+	if !hasArtifact(opts.Outputs, ArtifactSummary) {
+		return "", nil
+	}
+	infof("笔记已生成: %s", outputPath)
+	return outputPath, nil
+}
+
+// printEstimate 将输入路径和预估结果一次写入 DryRunOutput，批量处理时各文件的输出不会交错
+func (opts GenerateOptions) printEstimate(path string, est Estimate) error {
+	if opts.DryRunOutput == nil {
+		return nil
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s\n", path)
+	est.Print(&b)
+	if _, err := opts.DryRunOutput.Write(b.Bytes()); err != nil {
+		return fmt.Errorf("输出预估结果失败: %w", err)
+	}
+	return nil
+}
+
+// defaultNotePath 返回未指定输出路径时本地输入的笔记路径：与输入同名，写在输入旁边
+func defaultNotePath(input string, format Format) string {
+	return strings.TrimSuffix(input, filepath.Ext(input)) + format.Ext()
+}
+
+// summarizeOptions 返回生成摘要阶段使用的选项
+func (opts GenerateOptions) summarizeOptions(title, source string) SummarizeOptions {
+	return SummarizeOptions{
+		Ratio:             opts.Ratio,
+		Format:            opts.Format,
+		Mode:              opts.Mode,
+		Prompt:            opts.Prompt,
+		Title:             title,
+		Concurrency:       opts.Concurrency,
+		Language:          opts.Language,
+		Quiet:             opts.Quiet,
+		Stream:            opts.Stream,
+		Stats:             opts.Stats,
+		Source:            source,
+		IncludeTranscript: opts.IncludeTranscript,
+		ChunkOverlap:      opts.ChunkOverlap,
+		CleanTranscript:   opts.CleanTranscript,
+		Dedup:             opts.Dedup,
+		BOM:               opts.BOM,
+		TOC:               opts.TOC,
+		Refine:            opts.Refine,
+		RatioTolerance:    opts.RatioTolerance,
+		Words:             opts.Words,
+		Extractors:        opts.Extractors,
+		Metadata:          opts.Metadata,
+		MinLength:         opts.MinLength,
+		FailFast:          opts.FailFast,
+		Glossary:          opts.Glossary,
+		Preset:            opts.Preset,
+		Engine:            opts.SummarizeEngine,
+	}
+}
+
+// generateFromSubtitles 以字幕代替转录直接生成笔记，字幕的时间戳用于 -timestamps 和 -toc
+func generateFromSubtitles(ctx context.Context, config *Config, path, outputPath string, artifacts map[Artifact]string, opts GenerateOptions) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("读取字幕失败: %w", err)
+	}
+	transcript, err := subtitleTranscript(string(data))
+	if err != nil {
+		return "", err
+	}
+	infof("使用字幕 %s，跳过提取音频和转录 (%d条字幕)", path, len(transcript.Segments))
+
+	if opts.DryRun {
+		ratio := opts.Ratio
+		if opts.Words > 0 {
+			ratio = WordsRatio(transcript.Text, opts.Words)
+		}
+		return "", opts.printEstimate(path, EstimateFromText(config, transcript, ratio))
+	}
+
+	if err := writeArtifacts(transcript, artifacts); err != nil {
+		return "", err
+	}
+	if !hasArtifact(opts.Outputs, ArtifactSummary) {
+		return "", nil
+	}
+	infof("正在生成笔记摘要...")
+	title := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if err := summarizeTranscript(ctx, config, transcript, outputPath, opts.summarizeOptions(title, path)); err != nil {
+		return "", fmt.Errorf("生成摘要失败: %w", err)
+	}
+	infof("笔记已生成: %s", outputPath)
+	return outputPath, nil
+}
+
+// ExtractOptions 控制从视频中提取音频的方式
+type ExtractOptions struct {
+	// TrimSilence 为 true 时去除音频中的静音片段
+	TrimSilence bool
+	// SilenceThreshold 为判定为静音的音量阈值，如 -35dB
+	SilenceThreshold string
+	// SilenceDuration 为静音持续超过该时长才会被去除
+	SilenceDuration time.Duration
+	// Codec 为提取音频使用的编码，见 audioCodecs
+	Codec string
+	// SampleRate 和 Channels 为 0 时保持原样，如 16000 Hz 单声道可以大幅减小文件
+	SampleRate int
+	Channels   int
+	// Bitrate 为有损编码的码率，如 64k，为空时使用 ffmpeg 的默认值
+	Bitrate string
+	// AudioTrack 为要提取的音轨序号 (从0开始，只计音频流)，小于 0 时使用 ffmpeg 默认选择的音轨
+	AudioTrack int
+	// Start 和 End 只提取这段时间内的音频，End 为 0 时提取到结尾
+	Start time.Duration
+	End   time.Duration
+	// Normalize 为 true 时用 loudnorm 滤镜将响度统一到 Loudness (LUFS)
+	Normalize bool
+	Loudness  float64
+	// ExtraArgs 为用户追加的 ffmpeg 输出选项，如滤镜，放在程序管理的选项之后、编码参数之前
+	ExtraArgs []string
+}
+
+func (o ExtractOptions) Validate() error {
+	if err := validateAudioCodec(o.Codec); err != nil {
+		return err
+	}
+	if o.SampleRate < 0 || o.Channels < 0 {
+		return fmt.Errorf("采样率和声道数不能为负数")
+	}
+	if o.Start < 0 || o.End < 0 {
+		return fmt.Errorf("-start 和 -end 不能为负数")
+	}
+	if o.End > 0 && o.End <= o.Start {
+		return fmt.Errorf("-end (%s) 必须晚于 -start (%s)", formatTimestamp(o.End), formatTimestamp(o.Start))
+	}
+	if o.Normalize && (o.Loudness < -70 || o.Loudness > -5) {
+		return fmt.Errorf("-loudness 必须在 -70 到 -5 LUFS 之间")
+	}
+	return validateFFmpegArgs(o.ExtraArgs, o.TrimSilence || o.Normalize)
+}
+
+// mapArgs 返回选择音轨的 ffmpeg 参数
+func (o ExtractOptions) mapArgs() []string {
+	if o.AudioTrack < 0 {
+		return nil
+	}
+	return []string{"-map", fmt.Sprintf("0:a:%d", o.AudioTrack)}
+}
+
+// codec 返回提取音频使用的编码，未设置时为 mp3
+func (o ExtractOptions) codec() audioCodec {
+	if c, ok := audioCodecs[o.Codec]; ok {
+		return c
+	}
+	return audioCodecs[defaultAudioCodec]
+}
+
+// audioExt 返回提取出的音频文件的扩展名
+func (o ExtractOptions) audioExt() string {
+	return o.codec().ext
+}
+
+// encodeArgs 返回 ffmpeg 的音频编码参数
+func (o ExtractOptions) encodeArgs() []string {
+	c := o.codec()
+	args := []string{"-acodec", c.encoder}
+	if o.SampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(o.SampleRate))
+	}
+	if o.Channels > 0 {
+		args = append(args, "-ac", strconv.Itoa(o.Channels))
+	}
+	if o.Bitrate != "" && !c.lossless {
+		args = append(args, "-b:a", o.Bitrate)
+	}
+	return args
+}
+
+// extractAudio 从视频中提取音频；去除了静音或截取了时间段时返回用于换算回原视频时间的 timeMap，
+// 否则返回 nil。ctx 取消时终止 ffmpeg
+func extractAudio(ctx context.Context, config *Config, videoPath, audioPath string, opts ExtractOptions) (*timeMap, error) {
+	if opts.AudioTrack >= 0 {
+		if err := checkAudioTrack(ctx, config, videoPath, opts.AudioTrack); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Start > 0 || opts.End > 0 {
+		if err := checkRange(ctx, config, videoPath, opts.Start, opts.End); err != nil {
+			return nil, err
+		}
+	}
+	args := append([]string{"-y"}, opts.rangeArgs()...)
+	args = append(args, "-i", videoPath, "-vn")
+	args = append(args, opts.mapArgs()...)
+
+	var tm *timeMap
+	if opts.Start > 0 {
+		tm = &timeMap{offset: opts.Start}
+	}
+	// 先去除静音再统一响度，避免静音片段影响响度的测量
+	var filters []string
+	if opts.TrimSilence {
+		silences, err := detectSilence(ctx, config, videoPath, opts)
+		if err != nil {
+			return nil, err
+		}
+		if len(silences) > 0 {
+			filters = append(filters, silenceFilter(silences))
+			tm = &timeMap{removed: silences, offset: opts.Start}
+			infof("检测到%d段静音，将在转录前去除", len(silences))
+		}
+	}
+	if opts.Normalize {
+		filters = append(filters, loudnormFilter(opts.Loudness))
+	}
+	if len(filters) > 0 {
+		args = append(args, "-af", strings.Join(filters, ","))
+	}
+
+	args = append(args, opts.ExtraArgs...)
+	args = append(args, opts.encodeArgs()...)
+	args = append(args, audioPath)
+	cmd := exec.CommandContext(ctx, config.ffmpegBinary(), args...)
+	debugCommand(cmd)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		// 被取消时 ffmpeg 的输出没有参考价值，直接返回取消原因
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("提取音频被中断: %w", ctx.Err())
+		}
+		return nil, ffmpegError("ffmpeg", videoPath, err, output)
+	}
+	return tm, nil
+}
+
+// TranscribeOptions 控制音频转录的方式
+type TranscribeOptions struct {
+	SegmentTime time.Duration
+	// Timestamps 为 true 时请求 verbose_json 以获得片段级时间戳
+	Timestamps bool
+	// Format 为写入 outputPath 的格式，字幕格式会自动启用 Timestamps
+	Format Format
+	// Translate 为 true 时调用翻译接口，直接得到英文转录
+	Translate bool
+	// Quiet 为 true 时不显示分段转录进度
+	Quiet bool
+	// Diarize 为 true 时调用说话人分离，为每个片段标注说话人
+	Diarize bool
+	// CacheDir 不为空时在该目录缓存转录结果，相同音频和模型不再重复调用API
+	CacheDir string
+	// Language 为音频的语言代码 (ISO-639-1)，为空时由Whisper自动识别
+	Language string
+	// Prompt 为传给Whisper的提示文本，可提高专有名词的识别准确率
+	Prompt string
+	// Backend 为转录后端，openai 或 local
+	Backend string
+	// Engine 不为 nil 时代替 Backend 转录，用于接入其他转录服务或在测试中替换；
+	// 缓存无法区分不同的实现，使用 Engine 时不读写转录缓存
+	Engine Transcriber
+	// ResponseFormat 为请求 Whisper 的响应格式，为空时按是否需要时间戳自动选择
+	ResponseFormat string
+	// Temperature 为 Whisper 的采样温度，为 0 时使用接口默认值
+	Temperature float32
+	// Concurrency 为同时转录的音频分段数量上限
+	Concurrency int
+	// ConfidenceThreshold 小于 0 时请求 verbose_json，并对 avg_logprob 低于该值的时间段给出警告；
+	// 为 0 时不检查。只有 Whisper 模型返回置信度，其他模型忽略该选项并给出警告
+	ConfidenceThreshold float64
+	// Glossary 为术语表：术语附在 Prompt 之后作为转录提示，转录结束后更正术语的大小写、拆开的写法和拼写错误
+	Glossary []string
+
+	// timeMap 用于将警告中的时间换算回原视频的时间
+	timeMap *timeMap
+	// onPart 不为 nil 时，每段音频转录完成且之前的段都已完成后，按顺序传入该段新增的转录；
+	// 使用缓存或本地转录时在全部完成后传入一次。在转录的 goroutine 中调用，不能阻塞
+	onPart func(part *Transcript)
+}
+
+// Transcribe 转录音频并按 opts.Format 写入 outputPath，返回转录结果
+func Transcribe(ctx context.Context, config *Config, audioPath, outputPath string, opts TranscribeOptions) (*Transcript, error) {
+	// 字幕和说话人分离都需要片段级时间戳
+	if opts.Format.isSubtitle() || opts.Diarize {
+		opts.Timestamps = true
+	}
+	opts.Prompt = glossaryPrompt(opts.Prompt, opts.Glossary)
+	// 新的转录模型不返回时间戳，需要在计算缓存键和拼接片段之前调整选项
+	if opts.Engine == nil && (opts.Backend == "" || opts.Backend == transcriberOpenAI) {
+		if err := transcribeCapabilities(config.TranscribeModel).check(config.TranscribeModel, &opts); err != nil {
+			return nil, err
+		}
+	}
+
+	// 音频时长只用于运行报告，没有安装 ffprobe 时不统计
+	if duration, err := probeDuration(ctx, config, audioPath); err == nil {
+		metrics.addAudio(duration)
+	}
+
+	cacheDir := opts.CacheDir
+	if opts.Engine != nil {
+		cacheDir = ""
+	}
+	cache := newTranscriptCache(cacheDir)
+	key, err := cache.key(config, audioPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	// 后端没有逐段交付时 (使用缓存或本地转录)，在全部完成后一次交付
+	delivered := false
+	if onPart := opts.onPart; onPart != nil {
+		opts.onPart = func(part *Transcript) {
+			delivered = true
+			onPart(part)
+		}
+	}
+	transcript, ok := cache.Load(key)
+	if ok {
+		infof("使用缓存的转录结果")
+	} else {
+		transcriber := opts.Engine
+		if transcriber == nil {
+			if transcriber, err = newTranscriber(config, opts); err != nil {
+				return nil, err
+			}
+		}
+		transcript, err = transcriber.Transcribe(ctx, audioPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := cache.Store(key, transcript); err != nil {
+			warnf("写入转录缓存失败: %v", err)
+		}
+	}
+	if opts.onPart != nil && !delivered {
+		part := *transcript
+		part.Segments = slices.Clone(transcript.Segments)
+		if opts.Timestamps {
+			part.Text = part.joinSegments()
+		}
+		opts.onPart(&part)
+	}
+
+	if opts.Diarize {
+		infof("正在进行说话人分离...")
+		diarizer, err := newDiarizer(config)
+		if err != nil {
+			return nil, err
+		}
+		turns, err := diarizer.Diarize(ctx, audioPath)
+		if err != nil {
+			return nil, fmt.Errorf("说话人分离失败: %w", err)
+		}
+		assignSpeakers(transcript.Segments, turns)
+	}
+
+	if opts.Timestamps {
+		transcript.Text = transcript.joinSegments()
+	}
+	if n := correctGlossary(transcript, opts.Glossary); n > 0 {
+		infof("已按术语表更正转录中的 %d 处术语", n)
+	}
+	warnLowConfidence(transcript, opts.ConfidenceThreshold, opts.timeMap)
+	// 没有语音时不写入空白的转录文件
+	if strings.TrimSpace(transcript.Text) == "" {
+		return nil, markError(ErrNoSpeech, fmt.Errorf("没有检测到语音: %s 的转录结果为空，可能是静音或只有背景音乐", audioPath))
+	}
+
+	if err := writeOutput(outputPath, []byte(renderTranscript(transcript, opts.Format))); err != nil {
+		return nil, fmt.Errorf("写入转录文本失败: %w", err)
+	}
+
+	return transcript, nil
+}
+
+// 同时生成摘要的文本块数量默认值
+const DefaultConcurrency = 3
+
+// SummarizeOptions 控制摘要的生成方式与输出格式
+type SummarizeOptions struct {
+	Ratio  float64
+	Format Format
+	// Prompt 为摘要提示词模板，为空时使用内置模板
+	Prompt *template.Template
+	// Title 为Markdown输出的笔记标题
+	Title string
+	// Concurrency 为同时请求摘要的文本块数量上限
+	Concurrency int
+	// Language 为摘要使用的语言代码，为空时与原文一致
+	Language string
+	// Quiet 为 true 时不显示分块摘要进度
+	Quiet bool
+	// Mode 为 map-reduce 时会将各部分摘要再整合为一份完整的笔记
+	Mode Mode
+	// Stream 为 true 时将生成中的摘要实时输出到标准错误
+	Stream bool
+	// Source 为笔记来源（视频路径、URL 或转录文件），写入 JSON 输出
+	Source string
+	// Stats 为 true 时在标准错误输出字数、实际摘要比例和预计阅读时间
+	Stats bool
+	// IncludeTranscript 为 true 时将完整转录附在笔记之后
+	IncludeTranscript bool
+	// ChunkOverlap 为每块附带的前一块结尾的 token 数，用于保留衔接处的上下文
+	ChunkOverlap int
+	// CleanTranscript 为 true 时先清理转录文本 (去除语气词、规范标点、按停顿分段) 再生成摘要
+	CleanTranscript bool
+	// Dedup 为 true 时在逐块摘要之后再请求一次，删除各部分之间重复的要点
+	Dedup bool
+	// BOM 为 true 时在笔记开头写入 UTF-8 BOM，便于 Windows 上的编辑器识别编码
+	BOM bool
+	// TOC 为笔记开头的目录样式，只对带时间信息的部分生效
+	TOC TOC
+	// Extractors 为摘要之后额外提取的结构化信息，结果作为独立部分附在笔记之后
+	Extractors []Extractor
+	// Metadata 为 true 时在笔记旁写入 .meta.json；Duration 为来源的时长，
+	// 为 0 时按转录片段的结束时间计算
+	Metadata bool
+	Duration time.Duration
+	// Words 大于 0 时代替 Ratio，为最终笔记的目标字数：逐块摘要时按各块长度分配，
+	// map-reduce 模式下由整合步骤控制总字数
+	Words int
+	// Refine 为每部分摘要按长度调整的最多次数，为 0 时不调整；
+	// 摘要长度偏离目标超过 RatioTolerance (为 0 时使用 DefaultRatioTolerance) 时请求扩写或精简
+	Refine         int
+	RatioTolerance float64
+	// MinLength 大于 0 时，转录少于该字数 (中文的字与英文的词各计为 1) 则不生成摘要，
+	// 直接以转录作为笔记内容；问答卡片不受影响
+	MinLength int
+	// FailFast 为 true 时第一个部分失败就取消其余请求并返回该错误；
+	// 否则等待所有部分完成，失败的部分在笔记中留下占位说明，汇总所有错误返回
+	FailFast bool
+	// Glossary 为术语表，生成摘要前更正转录中术语的大小写、拆开的写法和拼写错误，并要求模型按术语表拼写
+	Glossary []string
+	// Preset 为 -preset 的名称，摘要时按该类视频的侧重点整理
+	Preset string
+	// Engine 不为 nil 时代替配置中的 summarizer 完成所有阶段的请求，用于接入其他服务或在测试中替换
+	Engine Summarizer
+
+	// prompts 为本次摘要使用的内置提示词，由 summarizeTranscript 按语言选择
+	prompts promptSet
+}
+
+// Summarize 读取 r 中的转录文本并生成摘要，r 可以是文件或标准输入
+func Summarize(ctx context.Context, config *Config, r io.Reader, outputPath string, opts SummarizeOptions) error {
+	// 读取转录文本
+	text, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("读取转录文本失败: %w", err)
+	}
+
+	// 字幕输入保留时间戳，笔记可以引用字幕中的时间
+	transcript, err := ParseTranscriptText(string(text))
+	if err != nil {
+		return err
+	}
+	if len(transcript.Segments) > 0 {
+		infof("检测到字幕输入，共%d条字幕", len(transcript.Segments))
+	}
+	return summarizeTranscript(ctx, config, transcript, outputPath, opts)
+}
+
+func summarizeTranscript(ctx context.Context, config *Config, transcript *Transcript, outputPath string, opts SummarizeOptions) error {
+	concurrency := opts.concurrency()
+	prepareTranscript(transcript, opts)
+	if strings.TrimSpace(transcript.Text) == "" {
+		return markError(ErrNoSpeech, errors.New("转录文本为空，没有可以生成笔记的内容"))
+	}
+
+	// 单独统计这份笔记的 token 用量，写入元数据
+	ctx, u := withUsage(ctx)
+
+	// 提示词语言与笔记语言一致，未指定 -lang 时跟随转录内容
+	opts.prompts = promptsFor(opts.Language, transcript)
+	cs, err := newChunkSummarizer(config, transcript, opts)
+	if err != nil {
+		return err
+	}
+
+	// 分割文本为多个块，避免超出token限制
+	chunks := transcript.chunks(cs.chunkTokens(), cs.count)
+	addOverlap(chunks, opts.ChunkOverlap, cs.count)
+	// -words 的目标字数针对最终的笔记：逐块摘要时换算为比例并按各块长度分配；
+	// map-reduce 模式下各块仍按比例摘要，由整合步骤控制总字数
+	var words []int
+	if opts.Words > 0 && (opts.Mode != ModeMapReduce || len(chunks) == 1) {
+		if length := textLength(transcript.Text); opts.Words > length {
+			warnf("目标字数 %d 超过原文的 %d 字，摘要不会比原文更长", opts.Words, length)
+		}
+		cs.ratio = WordsRatio(transcript.Text, opts.Words)
+		words = chunkWords(chunks, opts.Words)
+	}
+	// 转录过短时按比例摘要没有意义，摘要甚至可能比原文更长，直接以转录作为各部分的内容
+	verbatim := false
+	if length := textLength(transcript.Text); opts.MinLength > 0 && length < opts.MinLength {
+		if opts.Format.isFlashcards() {
+			warnf("转录只有 %d 字，生成的问答卡片可能很少", length)
+		} else {
+			warnf("转录只有 %d 字 (少于 -min-length %d)，忽略摘要比例，直接以转录作为笔记内容", length, opts.MinLength)
+			verbatim = true
+		}
+	}
+	// 按块序号写入，保证摘要顺序与视频时间线一致
+	sections := make([]Section, len(chunks))
+	for i, chunk := range chunks {
+		sections[i] = chunkSection(chunk)
+	}
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	// -fail-fast 时第一个错误取消其余进行中的请求
+	chunkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var firstErr error
+	var once sync.Once
+	fail := func(idx int, err error) {
+		errs[idx] = err
+		if opts.FailFast {
+			once.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		}
+	}
+	// 流式输出本身已能体现进度，不再显示进度条
+	bar := newProgress("正在生成摘要", len(chunks), opts.Quiet || opts.Stream)
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(idx int, chunk textChunk) {
+			defer wg.Done()
+
+			if verbatim {
+				sections[idx].Summary = chunk.Text
+				bar.Increment()
+				return
+			}
+
+			// 限制同时进行中的请求数量
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			target := 0
+			if words != nil {
+				target = words[idx]
+			}
+			summary, err := cs.summarize(chunkCtx, idx, len(chunks), chunk, target, chunkRange(chunks, idx, transcript))
+			if err != nil {
+				fail(idx, err)
+				return
+			}
+			sections[idx].Summary = summary
+			bar.Increment()
+		}(i, chunk)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	// 被中断时不写入不完整的笔记
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return cs.finish(ctx, transcript, outputPath, sections, errs, verbatim, u)
+}
+
+// concurrency 返回同时请求摘要的数量上限
+func (opts SummarizeOptions) concurrency() int {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+	// 流式输出时逐块生成，避免多个部分的内容在终端上交错
+	if opts.Stream {
+		concurrency = 1
+	}
+	return concurrency
+}
+
+// prepareTranscript 按选项清理转录并更正术语
+func prepareTranscript(transcript *Transcript, opts SummarizeOptions) {
+	if opts.CleanTranscript {
+		cleanTranscript(transcript)
+	}
+	if n := correctGlossary(transcript, opts.Glossary); n > 0 {
+		infof("已按术语表更正转录中的 %d 处术语", n)
+	}
+}
+
+// chunkSection 返回文本块对应的笔记部分，摘要由调用方填入
+func chunkSection(chunk textChunk) Section {
+	return Section{
+		Start:        chunk.Start,
+		Timed:        chunk.Timed,
+		Chapter:      chunk.Chapter,
+		Title:        chunk.Title,
+		SourceLength: textLength(chunk.Text),
+	}
+}
+
+// chunkSummarizer 保存逐块生成摘要所需的状态，summarizeTranscript 和 -pipeline 共用
+type chunkSummarizer struct {
+	config *Config
+	// stages 为各阶段的摘要后端，逐块摘要使用 map 阶段
+	stages  map[string]stageSummarizer
+	limiter *rateLimiter
+	tmpl    *template.Template
+	count   func(string) int
+	// ratio 为限制在 0.1-0.5 之间的摘要比例，-words 时由目标字数换算
+	ratio       float64
+	speakerHint string
+	opts        SummarizeOptions
+}
+
+// newChunkSummarizer 按 opts 创建逐块摘要使用的后端和模板，opts.prompts 必须已经选定
+func newChunkSummarizer(config *Config, transcript *Transcript, opts SummarizeOptions) (*chunkSummarizer, error) {
+	tmpl := opts.Prompt
+	if tmpl == nil {
+		tmpl = template.Must(template.New("prompt").Parse(opts.prompts.template(opts.Format)))
+	}
+
+	// 各阶段可以通过 profile 使用不同的模型和采样参数，逐块摘要使用 map 阶段的配置
+	stages, err := newStageSummarizers(config, opts.prompts.System, opts.Engine)
+	if err != nil {
+		return nil, err
+	}
+	cs := &chunkSummarizer{
+		config:  config,
+		stages:  stages,
+		limiter: newRateLimiter(config.RequestsPerMinute),
+		tmpl:    tmpl,
+		count:   tokenCounter(stages[stageMap].config.SummarizeModel),
+		ratio:   min(max(opts.Ratio, 0.1), 0.5),
+		opts:    opts,
+	}
+	if transcript.hasSpeakers() {
+		cs.speakerHint = opts.prompts.Speakers
+	}
+	return cs, nil
+}
+
+// chunkTokens 返回每块的 token 上限，超过模型上下文窗口能容纳的大小时给出警告
+func (cs *chunkSummarizer) chunkTokens() int {
+	mapConfig := cs.stages[stageMap].config
+	chunkTokens := mapConfig.chunkTokens()
+	if limit := mapConfig.maxChunkTokens(); chunkTokens > limit {
+		warnf("每块 %d tokens 超过 %s 的上下文窗口能容纳的约 %d tokens，请求可能失败，请减小 -chunk-size", chunkTokens, mapConfig.SummarizeModel, limit)
+	}
+	debugf("每个文本块最多 %d tokens", chunkTokens)
+	return chunkTokens
+}
+
+// summarize 生成第 idx 块的摘要；total 为总块数，未知时为 0；target 为 -words 分给该块的字数，
+// span 为错误信息中该块的时间范围。被内容过滤拦截时返回占位说明
+func (cs *chunkSummarizer) summarize(ctx context.Context, idx, total int, chunk textChunk, target int, span string) (string, error) {
+	opts := cs.opts
+	summarizer, mapConfig := cs.stages[stageMap], cs.stages[stageMap].config
+	if err := cs.limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+
+	prompt, err := buildPrompt(cs.tmpl, chunk.Text, cs.ratio, target)
+	if err != nil {
+		return "", err
+	}
+	prompt += opts.prompts.presetHint(opts.Preset) + opts.prompts.formatHint(opts.Format) +
+		opts.prompts.languageHint(opts.Language) + cs.speakerHint + opts.prompts.glossaryHint(opts.Glossary)
+	if target > 0 {
+		prompt += fmt.Sprintf(opts.prompts.Words, target)
+	}
+	if chunk.Title != "" {
+		prompt += fmt.Sprintf(opts.prompts.Chapter, chunk.Title)
+	}
+	if chunk.Context != "" {
+		prompt += opts.prompts.Context + chunk.Context
+	}
+
+	var stream io.Writer
+	if opts.Stream {
+		stream = os.Stderr
+		if total > 0 {
+			fmt.Fprintf(stream, "\n--- 第 %d/%d 部分 ---\n", idx+1, total)
+		} else {
+			fmt.Fprintf(stream, "\n--- 第 %d 部分 ---\n", idx+1)
+		}
+	}
+
+	maxTokens := completionTokens(mapConfig.SummarizeModel, cs.count(prompt), cs.count(chunk.Text), cs.ratio)
+	result, err := summarizer.Complete(ctx, prompt, maxTokens, stream)
+	summary := result.Text
+	switch {
+	case errors.Is(err, errContentFiltered):
+		// 被内容过滤拦截的部分留下占位说明，不影响其余部分
+		warnf("第%d部分%s被内容过滤拦截，已跳过: %v", idx+1, span, err)
+		summary = opts.prompts.Filtered
+	case err != nil:
+		return "", fmt.Errorf("生成第%d部分摘要失败%s: %w", idx+1, span, err)
+	case result.Model != mapConfig.SummarizeModel:
+		infof("第%d部分由备用模型 %s 生成", idx+1, result.Model)
+	default:
+		debugf("第%d部分由 %s 生成", idx+1, result.Model)
+	}
+	// 问答卡片的数量由知识点决定，不按长度调整
+	if opts.Refine > 0 && !opts.Format.isFlashcards() && err == nil {
+		if summary, err = refineLength(ctx, cs.stages[stageRefine], cs.limiter, summary, chunk.Text, cs.ratio, opts); err != nil {
+			return "", fmt.Errorf("第%d部分: %w", idx+1, err)
+		}
+	}
+	return summary, nil
+}
+
+// finish 汇总逐块摘要的结果：失败的部分留下占位说明，按选项去重、整合和提取结构化信息，
+// 写入笔记和元数据并执行后处理命令。errs 与 sections 一一对应；部分失败时返回 ErrPartial
+func (cs *chunkSummarizer) finish(ctx context.Context, transcript *Transcript, outputPath string, sections []Section, errs []error, verbatim bool, u *usage) error {
+	config, opts, stages, limiter := cs.config, cs.opts, cs.stages, cs.limiter
+	chunks := len(sections)
+
+	// 失败的部分留下占位说明，其余部分照常写入笔记，最后汇总所有错误
+	var failed []error
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+			sections[i].Summary = opts.prompts.Failed
+		}
+	}
+	var partialErr error
+	switch {
+	case len(failed) == chunks:
+		return fmt.Errorf("%d 个部分全部生成失败:\n%w", len(failed), errors.Join(failed...))
+	case len(failed) > 0:
+		warnf("%d/%d 个部分生成失败，笔记中对应位置留下占位说明", len(failed), chunks)
+		partialErr = markError(ErrPartial, fmt.Errorf("%d/%d 个部分生成失败:\n%w", len(failed), chunks, errors.Join(failed...)))
+	}
+	sections = mergeChapters(sections)
+
+	// 删除各部分之间重复的要点
+	var err error
+	if opts.Dedup && opts.Mode != ModeMapReduce && len(sections) > 1 && !verbatim {
+		infof("正在去除%d个部分之间重复的要点...", len(sections))
+		if sections, err = dedupSections(ctx, stages[stageDedup], limiter, stages[stageDedup].config, sections, opts); err != nil {
+			return err
+		}
+	}
+
+	// 将各部分摘要整合为一份完整的笔记
+	model := stages[stageMap].config.SummarizeModel
+	if opts.Mode == ModeMapReduce && len(sections) > 1 && !verbatim {
+		summaries := make([]string, len(sections))
+		for i, section := range sections {
+			summaries[i] = section.Summary
+		}
+
+		infof("正在整合%d个部分的摘要...", len(summaries))
+		reducer := stages[stageReduce]
+		summary, err := reduceSummaries(ctx, reducer, limiter, reducer.config, summaries, opts)
+		if err != nil {
+			return fmt.Errorf("整合摘要失败: %w", err)
+		}
+		model = reducer.config.SummarizeModel
+		sections = []Section{{Summary: summary, SourceLength: sourceLength(sections)}}
+	}
+
+	// 合并所有摘要部分
+	info := noteInfo{Title: opts.Title, Source: opts.Source, Model: model, TOC: opts.TOC}
+	if opts.IncludeTranscript {
+		info.Transcript = transcript.Text
+	}
+	if len(opts.Extractors) > 0 {
+		infof("正在提取%d项结构化信息...", len(opts.Extractors))
+		if info.Extractions, err = runExtractors(ctx, stages[stageExtract], limiter, stages[stageExtract].config, transcript, sections, opts); err != nil {
+			return err
+		}
+	}
+	combinedSummary, err := renderNotes(info, sections, opts.Format)
+	if err != nil {
+		return err
+	}
+
+	// 写入输出文件
+	if opts.BOM {
+		combinedSummary = utf8BOM + combinedSummary
+	}
+	if err := writeOutput(outputPath, []byte(combinedSummary)); err != nil {
+		return fmt.Errorf("写入摘要文件失败: %w", err)
+	}
+	if outputPath != StdoutPath {
+		metrics.addNote(outputPath)
+	}
+
+	if opts.Metadata {
+		if err := writeNoteMetadata(outputPath, transcript, model, chunks, u, opts); err != nil {
+			return err
+		}
+	}
+
+	if opts.Stats {
+		printStats(os.Stderr, sections, cs.ratio)
+	}
+
+	// 部分内容生成失败的笔记不交给后处理命令，避免推送或提交不完整的笔记
+	if len(config.PostHook) > 0 {
+		if partialErr != nil {
+			warnf("笔记不完整，跳过后处理命令")
+		} else if err := runPostHook(ctx, config, outputPath, opts); err != nil {
+			return err
+		}
+	}
+
+	return partialErr
+}
+
+// splitTextIntoChunks 按句子边界将文本切分为多个块，每块的 token 数不超过 limit；
+// 单个句子超过 limit 时才退回按单词切分
+func splitTextIntoChunks(text string, limit int, count func(string) int) []string {
+	var pieces []string
+	for _, sentence := range splitSentences(text) {
+		if count(sentence) > limit {
+			pieces = append(pieces, splitWords(sentence, limit, count)...)
+		} else {
+			pieces = append(pieces, sentence)
+		}
+	}
+
+	join := func(i, j int) string {
+		chunk := ""
+		for _, piece := range pieces[i:j] {
+			chunk = joinText(chunk, piece)
+		}
+		return chunk
+	}
+	var chunks []string
+	start := 0
+	for _, end := range packChunks(len(pieces), limit, join, count) {
+		chunks = append(chunks, join(start, end))
+		start = end
+	}
+	return chunks
+}
+
+// splitWords 按单词边界将文本切分为多个块，每块的 token 数不超过 limit
+func splitWords(text string, limit int, count func(string) int) []string {
+	words := splitLongWords(strings.Fields(text), limit, count)
+	join := func(i, j int) string {
+		return strings.Join(words[i:j], " ")
+	}
+	var chunks []string
+	start := 0
+	for _, end := range packChunks(len(words), limit, join, count) {
+		chunks = append(chunks, join(start, end))
+		start = end
+	}
+	return chunks
+}
+
+// packChunks 将 n 个片段按顺序装入 token 数不超过 limit 的块，返回每块结束处的下标；text(i, j) 返回
+// 片段 i 到 j-1 组成一块时的文本。先按各片段单独统计的 token 数之和决定每块的片段数，再核对拼接后的
+// 实际 token 数：分隔的空格和粗略估算时每段的取整都会使实际数量多于各段之和，超出时二分查找能放下的片段数。
+// 单个片段总是可以单独成块，调用方需保证单个片段不超过 limit
+func packChunks(n, limit int, text func(i, j int) string, count func(string) int) []int {
+	var ends []int
+	for i := 0; i < n; {
+		j, tokens := i, 0
+		for j < n {
+			t := count(text(j, j+1))
+			if j > i && tokens+t > limit {
+				break
+			}
+			tokens += t
+			j++
+		}
+		if j-i > 1 && count(text(i, j)) > limit {
+			lo, hi := i+1, j-1
+			for lo < hi {
+				mid := (lo + hi + 1) / 2
+				if count(text(i, mid)) <= limit {
+					lo = mid
+				} else {
+					hi = mid - 1
+				}
+			}
+			j = lo
+		}
+		ends = append(ends, j)
+		i = j
+	}
+	return ends
+}
+
+// splitLongWords 将 token 数超过 limit 的“词”按字符边界拆开；
+// 中文、日文等不以空格分词的文本往往整段都是一个词
+func splitLongWords(words []string, limit int, count func(string) int) []string {
+	var result []string
+	for _, word := range words {
+		for count(word) > limit {
+			runes := []rune(word)
+			n := min(len(runes), limit)
+			for n > 1 && count(string(runes[:n])) > limit {
+				n = n * 3 / 4
+			}
+			result = append(result, string(runes[:n]))
+			word = string(runes[n:])
+		}
+		result = append(result, word)
+	}
+	return result
+}
@@ -0,0 +1,365 @@
+package videonote
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// apiCase 为成功、限流后重试成功和返回无法解析的内容三种情况
+type apiCase struct {
+	name        string
+	rateLimited map[string]int
+	malformed   string
+	wantErr     bool
+	wantCalls   map[string]int
+}
+
+func (c apiCase) setup(api *fakeAPI) {
+	for path, n := range c.rateLimited {
+		api.RateLimited[path] = n
+	}
+	if c.malformed != "" {
+		api.Malformed[c.malformed] = true
+	}
+}
+
+func (c apiCase) checkCalls(t *testing.T, api *fakeAPI) {
+	t.Helper()
+	for path, want := range c.wantCalls {
+		if got := api.Calls(path); got != want {
+			t.Errorf("%s 收到 %d 个请求，期望 %d 个", path, got, want)
+		}
+	}
+}
+
+// testGenerateOptions 返回与 generate 命令默认参数一致的选项，音频输入直接转录，不需要 ffmpeg
+func testGenerateOptions() GenerateOptions {
+	return GenerateOptions{
+		Ratio:       0.2,
+		Format:      FormatText,
+		Mode:        ModeFlat,
+		SegmentTime: 10 * time.Minute,
+		Quiet:       true,
+		Extract:     ExtractOptions{AudioTrack: -1},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []apiCase{
+		{
+			name:      "success",
+			wantCalls: map[string]int{transcriptionsPath: 1, completionsPath: 1},
+		},
+		{
+			name:        "rate limited",
+			rateLimited: map[string]int{transcriptionsPath: 1, completionsPath: 1},
+			wantCalls:   map[string]int{transcriptionsPath: 2, completionsPath: 2},
+		},
+		{
+			name:      "malformed completion",
+			malformed: completionsPath,
+			wantErr:   true,
+			wantCalls: map[string]int{transcriptionsPath: 1, completionsPath: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI(t)
+			api.Complete = func(string) string { return "测试摘要" }
+			tt.setup(api)
+
+			input := writeTestFile(t, "lecture.mp3", "fake audio")
+			output := filepath.Join(t.TempDir(), "lecture.txt")
+			path, err := Generate(context.Background(), api.config(t), input, output, testGenerateOptions())
+			tt.checkCalls(t, api)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("期望返回错误")
+				}
+				if _, err := os.Stat(output); !os.IsNotExist(err) {
+					t.Errorf("失败时不应写入笔记: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path != output {
+				t.Errorf("返回的路径为 %q，期望 %q", path, output)
+			}
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), "测试摘要") {
+				t.Errorf("笔记中没有摘要:\n%s", data)
+			}
+			if prompts := api.Prompts(); len(prompts) == 0 || !strings.Contains(prompts[len(prompts)-1], api.Transcript) {
+				t.Errorf("摘要请求中没有转录文本: %q", prompts)
+			}
+		})
+	}
+}
+
+func TestTranscribe(t *testing.T) {
+	tests := []apiCase{
+		{
+			name:      "success",
+			wantCalls: map[string]int{transcriptionsPath: 1},
+		},
+		{
+			name:        "rate limited",
+			rateLimited: map[string]int{transcriptionsPath: 2},
+			wantCalls:   map[string]int{transcriptionsPath: 3},
+		},
+		{
+			name:      "malformed response",
+			malformed: transcriptionsPath,
+			wantErr:   true,
+			wantCalls: map[string]int{transcriptionsPath: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI(t)
+			tt.setup(api)
+
+			input := writeTestFile(t, "talk.mp3", "fake audio")
+			output := filepath.Join(t.TempDir(), "talk.srt")
+			// 字幕需要 verbose_json，JSON 无法解析时才会出错
+			transcript, err := Transcribe(context.Background(), api.config(t), input, output, TranscribeOptions{
+				Format:      FormatSRT,
+				SegmentTime: 10 * time.Minute,
+				Quiet:       true,
+			})
+			tt.checkCalls(t, api)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("期望返回错误")
+				}
+				if _, err := os.Stat(output); !os.IsNotExist(err) {
+					t.Errorf("失败时不应写入转录: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if transcript.Text != api.Transcript {
+				t.Errorf("转录为 %q，期望 %q", transcript.Text, api.Transcript)
+			}
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), "00:00:00,000 --> 00:00:05,000") {
+				t.Errorf("字幕中没有片段时间:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []apiCase{
+		{
+			name:      "success",
+			wantCalls: map[string]int{completionsPath: 1},
+		},
+		{
+			name:        "rate limited",
+			rateLimited: map[string]int{completionsPath: 2},
+			wantCalls:   map[string]int{completionsPath: 3},
+		},
+		{
+			name:      "malformed response",
+			malformed: completionsPath,
+			wantErr:   true,
+			wantCalls: map[string]int{completionsPath: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI(t)
+			api.Complete = func(string) string { return "测试摘要" }
+			tt.setup(api)
+
+			output := filepath.Join(t.TempDir(), "notes.md")
+			err := Summarize(context.Background(), api.config(t), strings.NewReader("今天讨论了测试的写法。"), output, SummarizeOptions{
+				Ratio:  0.2,
+				Format: FormatMarkdown,
+				Mode:   ModeFlat,
+				Title:  "测试",
+				Quiet:  true,
+			})
+			tt.checkCalls(t, api)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("期望返回错误")
+				}
+				if _, err := os.Stat(output); !os.IsNotExist(err) {
+					t.Errorf("失败时不应写入笔记: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), "测试摘要") {
+				t.Errorf("笔记中没有摘要:\n%s", data)
+			}
+		})
+	}
+}
+
+// 与 summarize -i - 相同：从标准输入读取转录，笔记写入标准输出
+func TestSummarizeStdin(t *testing.T) {
+	api := newFakeAPI(t)
+	api.Complete = func(string) string { return "来自标准输入的摘要" }
+
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStdin, oldStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdinR, stdoutW
+	t.Cleanup(func() { os.Stdin, os.Stdout = oldStdin, oldStdout })
+
+	go func() {
+		io.WriteString(stdinW, "这是通过管道传入的转录文本。")
+		stdinW.Close()
+	}()
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(stdoutR)
+		out <- string(data)
+	}()
+
+	err = Summarize(context.Background(), api.config(t), os.Stdin, StdoutPath, SummarizeOptions{
+		Ratio:  0.2,
+		Format: FormatText,
+		Mode:   ModeFlat,
+		Quiet:  true,
+	})
+	stdoutW.Close()
+	notes := <-out
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompts := api.Prompts(); len(prompts) != 1 || !strings.Contains(prompts[0], "这是通过管道传入的转录文本。") {
+		t.Errorf("摘要请求中没有标准输入的内容: %q", prompts)
+	}
+	if !strings.Contains(notes, "来自标准输入的摘要") {
+		t.Errorf("标准输出为 %q", notes)
+	}
+}
+
+// 取消 ctx 时应终止进行中的 ffmpeg，不留下仍在运行的进程
+func TestExtractAudioCanceled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("模拟的 ffmpeg 为 shell 脚本")
+	}
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "ffmpeg.pid")
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	script := "#!/bin/sh\necho $$ > " + pidFile + "\nexec sleep 30\n"
+	if err := os.WriteFile(ffmpeg, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{FFmpegPath: ffmpeg}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := extractAudio(ctx, config, writeTestFile(t, "talk.mp4", "fake video"), filepath.Join(dir, "audio.mp3"), ExtractOptions{AudioTrack: -1})
+		done <- err
+	}()
+
+	// 等模拟的 ffmpeg 启动后再取消
+	var pid int
+	for deadline := time.Now().Add(5 * time.Second); pid == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("模拟的 ffmpeg 没有启动")
+		}
+		data, _ := os.ReadFile(pidFile)
+		pid, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v，期望 context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("取消后 extractAudio 没有返回")
+	}
+	// extractAudio 返回时进程已被终止并回收
+	if p, err := os.FindProcess(pid); err == nil {
+		if err := p.Signal(syscall.Signal(0)); !errors.Is(err, os.ErrProcessDone) {
+			p.Kill()
+			t.Errorf("ffmpeg 进程 %d 在取消后仍在运行: %v", pid, err)
+		}
+	}
+}
+
+// 没有语音时返回 ErrNoSpeech，不写入空白的笔记
+func TestGenerateNoSpeech(t *testing.T) {
+	for name, transcript := range map[string]string{"empty": "", "whitespace": " \n\t "} {
+		t.Run(name, func(t *testing.T) {
+			api := newFakeAPI(t)
+			api.Transcript = transcript
+
+			input := writeTestFile(t, "silence.mp3", "fake audio")
+			output := filepath.Join(t.TempDir(), "silence.txt")
+			_, err := Generate(context.Background(), api.config(t), input, output, testGenerateOptions())
+			if !errors.Is(err, ErrNoSpeech) {
+				t.Fatalf("err = %v，期望 ErrNoSpeech", err)
+			}
+			if n := api.Calls(completionsPath); n != 0 {
+				t.Errorf("没有语音时仍请求了 %d 次摘要", n)
+			}
+			entries, err := os.ReadDir(filepath.Dir(output))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("没有语音时不应写入文件，输出目录中有 %d 个文件", len(entries))
+			}
+		})
+	}
+
+	t.Run("summarize", func(t *testing.T) {
+		api := newFakeAPI(t)
+		output := filepath.Join(t.TempDir(), "notes.txt")
+		err := Summarize(context.Background(), api.config(t), strings.NewReader("\n\n"), output, SummarizeOptions{
+			Ratio:  0.2,
+			Format: FormatText,
+			Mode:   ModeFlat,
+			Quiet:  true,
+		})
+		if !errors.Is(err, ErrNoSpeech) {
+			t.Fatalf("err = %v，期望 ErrNoSpeech", err)
+		}
+		if _, err := os.Stat(output); !os.IsNotExist(err) {
+			t.Errorf("没有内容时不应写入笔记: %v", err)
+		}
+	})
+}
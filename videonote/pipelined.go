@@ -0,0 +1,193 @@
+package videonote
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// notePipeline 为 -pipeline 模式：每段音频转录完成后立即开始摘要，已完成的部分按顺序逐步写入笔记，
+// 全部转录完成后再去重、整合和提取结构化信息，写入最终的笔记
+type notePipeline struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	parent     context.Context
+	config     *Config
+	outputPath string
+	opts       SummarizeOptions
+	u          *usage
+	sem        chan struct{}
+	wg         sync.WaitGroup
+
+	mu sync.Mutex
+	// cs 在收到第一段转录时创建，提示词语言跟随转录内容
+	cs          *chunkSummarizer
+	chunkTokens int
+	sections    []Section
+	errs        []error
+	done        []bool
+	// err 为创建摘要后端失败或 -fail-fast 时的第一个错误，之后不再开始新的摘要
+	err error
+	// flushed 为已写入笔记的部分数量
+	flushed int
+	// final 为 true 表示最终的笔记已经写入
+	final   bool
+	aborted bool
+}
+
+// newNotePipeline 创建 -pipeline 使用的摘要流水线，调用方需要 defer abort()
+func newNotePipeline(ctx context.Context, config *Config, outputPath string, opts SummarizeOptions) *notePipeline {
+	parent := ctx
+	ctx, u := withUsage(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	return &notePipeline{
+		ctx:        ctx,
+		cancel:     cancel,
+		parent:     parent,
+		config:     config,
+		outputPath: outputPath,
+		opts:       opts,
+		u:          u,
+		sem:        make(chan struct{}, opts.concurrency()),
+	}
+}
+
+// add 切分新转录的一段并开始摘要，不会阻塞；在转录的 goroutine 中按时间顺序调用
+func (p *notePipeline) add(part *Transcript) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil || p.aborted {
+		return
+	}
+
+	// 清理和更正只作用于这一段，完整转录在 finish 中另行处理
+	if p.opts.CleanTranscript {
+		cleanTranscript(part)
+	}
+	if n := correctGlossary(part, p.opts.Glossary); n > 0 {
+		debugf("已按术语表更正这一段转录中的 %d 处术语", n)
+	}
+	if strings.TrimSpace(part.Text) == "" {
+		return
+	}
+	if p.cs == nil {
+		p.opts.prompts = promptsFor(p.opts.Language, part)
+		cs, err := newChunkSummarizer(p.config, part, p.opts)
+		if err != nil {
+			p.err = err
+			return
+		}
+		p.cs, p.chunkTokens = cs, cs.chunkTokens()
+	}
+
+	chunks := part.chunks(p.chunkTokens, p.cs.count)
+	addOverlap(chunks, p.opts.ChunkOverlap, p.cs.count)
+	for i, chunk := range chunks {
+		idx := len(p.sections)
+		p.sections = append(p.sections, chunkSection(chunk))
+		p.errs = append(p.errs, nil)
+		p.done = append(p.done, false)
+		span := chunkRange(chunks, i, part)
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+
+			// 限制同时进行中的请求数量
+			p.sem <- struct{}{}
+			defer func() { <-p.sem }()
+
+			summary, err := p.cs.summarize(p.ctx, idx, 0, chunk, 0, span)
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if err != nil {
+				p.errs[idx] = err
+				// -fail-fast 时第一个错误取消其余进行中的请求
+				if p.opts.FailFast && p.err == nil {
+					p.err = err
+					p.cancel()
+				}
+			} else {
+				p.sections[idx].Summary = summary
+			}
+			p.done[idx] = true
+			p.flush()
+		}()
+	}
+}
+
+// flush 将按顺序已完成的部分写入笔记，调用方需持有 p.mu；写入标准输出时只在最后写入一次
+func (p *notePipeline) flush() {
+	n := p.flushed
+	for n < len(p.done) && p.done[n] {
+		n++
+	}
+	if n == p.flushed || p.err != nil || p.aborted || p.outputPath == StdoutPath {
+		return
+	}
+
+	sections := make([]Section, n)
+	copy(sections, p.sections[:n])
+	for i := range sections {
+		if p.errs[i] != nil {
+			sections[i].Summary = p.opts.prompts.Failed
+		}
+	}
+	info := noteInfo{Title: p.opts.Title, Source: p.opts.Source, Model: p.cs.stages[stageMap].config.SummarizeModel}
+	notes, err := renderNotes(info, mergeChapters(sections), p.opts.Format)
+	if err == nil {
+		if p.opts.BOM {
+			notes = utf8BOM + notes
+		}
+		err = writeOutput(p.outputPath, []byte(notes))
+	}
+	if err != nil {
+		warnf("写入进行中的笔记失败: %v", err)
+		return
+	}
+	p.flushed = n
+	infof("已写入前%d个部分的摘要: %s", n, p.outputPath)
+}
+
+// finish 等待所有摘要完成，按完整的转录写入最终的笔记；transcript 为转录的完整结果
+func (p *notePipeline) finish(transcript *Transcript) error {
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	// 被中断时不写入不完整的笔记
+	if err := p.parent.Err(); err != nil {
+		return err
+	}
+	if p.cs == nil || len(p.sections) == 0 {
+		return markError(ErrNoSpeech, errors.New("转录文本为空，没有可以生成笔记的内容"))
+	}
+
+	prepareTranscript(transcript, p.opts)
+	err := p.cs.finish(p.ctx, transcript, p.outputPath, p.sections, p.errs, false, p.u)
+	p.final = err == nil || errors.Is(err, ErrPartial)
+	return err
+}
+
+// abort 取消进行中的摘要并等待其结束，可以重复调用；没有写入最终的笔记时删除已写入的不完整笔记
+func (p *notePipeline) abort() {
+	p.cancel()
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.aborted {
+		return
+	}
+	p.aborted = true
+	if !p.final && p.flushed > 0 {
+		if err := os.Remove(p.outputPath); err != nil && !os.IsNotExist(err) {
+			warnf("删除不完整的笔记失败: %v", err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package videonote
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Preset 为一组适合某类视频的参数，Flags 中的键为参数名 (不含 -)；
+// 命令行中明确指定的参数优先于预设，命令不支持的参数会被忽略
+type Preset struct {
+	Description string
+	Flags       map[string]string
+}
+
+// presets 为内置的预设，修改时需同步更新 README；提示词中的侧重点见 promptSet.Presets
+var presets = map[string]Preset{
+	"meeting": {
+		Description: "会议：整合为一份纪要，标注发言人，列出决定和行动事项",
+		Flags: map[string]string{
+			"format":  "md",
+			"ratio":   "0.25",
+			"mode":    "map-reduce",
+			"diarize": "true",
+			"extract": "actions",
+		},
+	},
+	"lecture": {
+		Description: "讲座和课程：按时间分段的详细笔记，保留概念、定义和例子",
+		Flags: map[string]string{
+			"format":           "md",
+			"ratio":            "0.3",
+			"timestamps":       "true",
+			"clean-transcript": "true",
+			"extract":          "topics",
+		},
+	},
+	"podcast": {
+		Description: "播客：围绕主题整合的简明笔记，标注主持人与嘉宾",
+		Flags: map[string]string{
+			"format":           "md",
+			"ratio":            "0.2",
+			"mode":             "map-reduce",
+			"diarize":          "true",
+			"clean-transcript": "true",
+			"extract":          "topics,entities",
+		},
+	},
+	"interview": {
+		Description: "访谈：按时间分段，保留问答的对应关系和受访者的原话要点",
+		Flags: map[string]string{
+			"format":           "md",
+			"ratio":            "0.3",
+			"timestamps":       "true",
+			"diarize":          "true",
+			"clean-transcript": "true",
+		},
+	},
+}
+
+// PresetNames 返回所有预设的名称，按字母排序
+func PresetNames() string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// ApplyPreset 将预设 name 中的参数设置到 fs，必须在解析命令行之后调用；命令行中明确指定的参数
+// 保持不变。没有配置 diarize_command 时不启用说话人分离，避免预设本身导致运行失败
+func ApplyPreset(fs *flag.FlagSet, config *Config, name string) error {
+	if name == "" {
+		return nil
+	}
+	preset, ok := presets[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("不支持的预设: %s (可选: %s)", name, PresetNames())
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	var applied []string
+	for key, value := range preset.Flags {
+		if explicit[key] || fs.Lookup(key) == nil {
+			continue
+		}
+		if key == "diarize" && len(config.DiarizeCommand) == 0 {
+			infof("未配置 diarize_command，预设 %s 不进行说话人分离", name)
+			continue
+		}
+		if err := fs.Set(key, value); err != nil {
+			return fmt.Errorf("应用预设 %s 失败: -%s: %w", name, key, err)
+		}
+		applied = append(applied, fmt.Sprintf("-%s %s", key, value))
+	}
+	sort.Strings(applied)
+	debugf("预设 %s: %s", name, strings.Join(applied, " "))
+	return nil
+}
+
+// presetHint 返回预设对应的摘要侧重点，name 为空或没有对应的说明时不做要求
+func (p promptSet) presetHint(name string) string {
+	return p.Presets[strings.ToLower(name)]
+}
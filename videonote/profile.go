@@ -0,0 +1,124 @@
+package videonote
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 摘要流水线中调用对话模型的阶段，可在配置的 stages 中为每个阶段指定 profile
+const (
+	stageMap     = "map"     // 逐块生成摘要
+	stageReduce  = "reduce"  // map-reduce 模式整合各部分摘要
+	stageDedup   = "dedup"   // -dedup 去除重复要点
+	stageRefine  = "refine"  // -refine 调整摘要长度
+	stageExtract = "extract" // -extract 提取结构化信息
+)
+
+var stageNames = []string{stageMap, stageReduce, stageDedup, stageRefine, stageExtract}
+
+// Profile 为一组摘要请求参数，未设置的字段沿用全局配置 (包括命令行参数)
+type Profile struct {
+	Model       string   `json:"model"`
+	Temperature *float32 `json:"temperature"`
+	TopP        *float32 `json:"top_p"`
+	MaxTokens   int      `json:"max_tokens"`
+}
+
+// forStage 返回应用了该阶段 profile 的配置，没有为该阶段指定 profile 时返回 c 本身
+func (c *Config) forStage(stage string) *Config {
+	profile, ok := c.Profiles[c.Stages[stage]]
+	if !ok {
+		return c
+	}
+	stageConfig := *c
+	if profile.Model != "" {
+		stageConfig.SummarizeModel = profile.Model
+	}
+	if profile.Temperature != nil {
+		stageConfig.Temperature = profile.Temperature
+	}
+	if profile.TopP != nil {
+		stageConfig.TopP = profile.TopP
+	}
+	if profile.MaxTokens > 0 {
+		stageConfig.MaxTokens = profile.MaxTokens
+	}
+	return &stageConfig
+}
+
+// validateProfiles 检查 profiles 中的参数，以及 stages 引用的阶段和 profile 是否存在
+func (c *Config) validateProfiles() error {
+	for name, profile := range c.Profiles {
+		if c.BaseURL == "" && isTranscriptionModel(profile.Model) {
+			return fmt.Errorf("profile %s 的 model %q 是语音转录模型，不能用于生成摘要", name, profile.Model)
+		}
+		if profile.Temperature != nil {
+			if err := validateTemperature(*profile.Temperature); err != nil {
+				return fmt.Errorf("profile %s: %w", name, err)
+			}
+		}
+		if profile.TopP != nil {
+			if err := validateTopP(*profile.TopP); err != nil {
+				return fmt.Errorf("profile %s: %w", name, err)
+			}
+		}
+		if profile.MaxTokens < 0 {
+			return fmt.Errorf("profile %s 的 max_tokens 不能为负数", name)
+		}
+	}
+
+	for stage, name := range c.Stages {
+		if !isStage(stage) {
+			return fmt.Errorf("stages 中有未知的阶段 %q (可选: %s)", stage, strings.Join(stageNames, ", "))
+		}
+		if _, ok := c.Profiles[name]; !ok {
+			return fmt.Errorf("阶段 %s 使用的 profile %q 不存在 (已定义: %s)", stage, name, profileNames(c.Profiles))
+		}
+	}
+	return nil
+}
+
+func isStage(s string) bool {
+	for _, stage := range stageNames {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+func profileNames(profiles map[string]Profile) string {
+	if len(profiles) == 0 {
+		return "无"
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// stageSummarizer 为某个阶段的摘要后端，config 为应用了该阶段 profile 的配置
+type stageSummarizer struct {
+	Summarizer
+	config *Config
+}
+
+// newStageSummarizers 为每个阶段创建摘要后端，system 为默认的系统消息；engine 不为 nil 时所有阶段都使用 engine
+func newStageSummarizers(config *Config, system string, engine Summarizer) (map[string]stageSummarizer, error) {
+	stages := make(map[string]stageSummarizer, len(stageNames))
+	for _, stage := range stageNames {
+		stageConfig := config.forStage(stage)
+		summarizer := engine
+		if summarizer == nil {
+			var err error
+			if summarizer, err = newSummarizer(stageConfig, system); err != nil {
+				return nil, err
+			}
+		}
+		stages[stage] = stageSummarizer{Summarizer: summarizer, config: stageConfig}
+	}
+	return stages, nil
+}
@@ -0,0 +1,63 @@
+package videonote
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 进度条宽度（字符数）
+const progressBarWidth = 30
+
+// progress 报告分块任务的完成进度；终端中原地刷新进度条，重定向时逐行输出日志
+type progress struct {
+	mu    sync.Mutex
+	label string
+	total int
+	done  int
+	quiet bool
+	tty   bool
+}
+
+func newProgress(label string, total int, quiet bool) *progress {
+	// 进度属于 info 级别；JSON 日志时逐行输出，不刷新进度条
+	quiet = quiet || logLevel.Level() > slog.LevelInfo
+	p := &progress{label: label, total: total, quiet: quiet, tty: IsTerminal(os.Stderr) && !jsonLogs}
+	p.render()
+	return p
+}
+
+// Increment 标记完成一项并刷新显示，可在多个 goroutine 中并发调用
+func (p *progress) Increment() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.render()
+}
+
+func (p *progress) render() {
+	if p.quiet || p.total <= 1 {
+		return
+	}
+
+	if !p.tty {
+		if p.done > 0 {
+			infof("%s %d/%d", p.label, p.done, p.total)
+		}
+		return
+	}
+
+	filled := progressBarWidth * p.done / p.total
+	bar := colorize(strings.Repeat("=", filled), ansiGreen) + strings.Repeat(" ", progressBarWidth-filled)
+	fmt.Fprintf(os.Stderr, "\r%s [%s] %d/%d", p.label, bar, p.done, p.total)
+	if p.done == p.total {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
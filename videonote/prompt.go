@@ -0,0 +1,247 @@
+package videonote
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"text/template"
+)
+
+const defaultPromptTemplate = `请为以下视频转录内容生成详细的笔记摘要，保留关键信息和重要细节:
+			
+内容:
+{{.Text}}
+
+请生成一份简洁但信息丰富的摘要，约占原文长度的{{.Percent}}%。`
+
+const englishPromptTemplate = `Write detailed notes summarizing the following video transcript, keeping the key information and important details:
+
+Transcript:
+{{.Text}}
+
+Produce a concise but informative summary of about {{.Percent}}% of the original length.`
+
+const flashcardsPromptTemplate = `请根据以下视频转录内容制作用于间隔重复记忆的问答卡片。每张卡片只考察一个关键知识点，问题具体明确、脱离上下文也能看懂，答案简洁准确:
+
+内容:
+{{.Text}}`
+
+const englishFlashcardsPromptTemplate = `Create spaced-repetition flashcards from the following video transcript. Each card should test a single key point; questions must be specific and understandable without further context, and answers short and accurate:
+
+Transcript:
+{{.Text}}`
+
+// promptSet 为一种语言的内置提示词，包括摘要模板和追加在提示词末尾的各项要求
+type promptSet struct {
+	// System 为默认的系统消息，设定助手的角色和输出规范
+	System string
+	// Summary 为默认的摘要模板
+	Summary string
+	// Flashcards 为问答卡片格式使用的默认模板
+	Flashcards string
+	// Reduce 为 map-reduce 模式整合各部分摘要的提示词，%s 为各部分摘要
+	Reduce string
+	// Dedup 为 -dedup 去除各部分间重复要点的提示词，%s 为各部分摘要
+	Dedup string
+	// Expand 和 Condense 为 -refine 扩写和精简摘要的提示词，%d 为目标字数
+	Expand   string
+	Condense string
+	// Part 为整合和去重时每部分摘要的标题，%d 为序号
+	Part     string
+	Markdown string
+	// Cards 要求模型按 Q:/A: 格式输出卡片，便于解析
+	Cards    string
+	Speakers string
+	// Chapter 中的 %s 为章节标题
+	Chapter string
+	Context string
+	// Language 中的 %s 为笔记使用的语言名称
+	Language string
+	// Filtered 为被内容过滤拦截的部分在笔记中的占位说明
+	Filtered string
+	// Failed 为生成失败的部分在笔记中的占位说明
+	Failed string
+	// Words 中的 %d 为 -words 的目标字数
+	Words string
+	// Glossary 中的 %s 为 -glossary 中的术语
+	Glossary string
+	// Presets 为 -preset 对应的摘要侧重点
+	Presets map[string]string
+	// Extract 为 -extract 的提示词，第一个 %s 为 Extractors 中对应的说明，第二个为内容
+	Extract    string
+	Extractors map[Extractor]string
+	// Names 为语言代码对应的语言名称
+	Names map[string]string
+}
+
+// 内置的提示词，按提示词语言区分
+var promptSets = map[string]promptSet{
+	"zh": {
+		System:     "你是一名专业的笔记整理助手，负责将视频转录整理为准确、条理清晰的学习笔记。只依据提供的内容写作，不要编造信息；保留专有名词、数字和关键结论；直接输出笔记正文，不要添加开场白或结束语。",
+		Summary:    defaultPromptTemplate,
+		Flashcards: flashcardsPromptTemplate,
+		Reduce:     reducePrompt,
+		Dedup:      dedupPrompt,
+		Expand:     expandPrompt,
+		Condense:   condensePrompt,
+		Part:       "【第 %d 部分】",
+		Markdown:   "\n\n请使用Markdown格式输出：用“### ”作为小标题划分要点，用“- ”列出关键内容，不要输出一级或二级标题。",
+		Cards:      "\n\n请严格按以下格式逐张输出卡片，卡片之间空一行，不要输出其他内容：\nQ: 问题\nA: 答案",
+		Speakers:   "\n\n转录内容中的 [Speaker N] 标记了说话人，请在摘要中保留观点与说话人的对应关系。",
+		Chapter:    "\n\n这部分内容属于视频章节「%s」。",
+		Context:    "\n\n以下是上一部分结尾的内容，仅用于理解上下文，不要写入本部分的摘要：\n",
+		Language:   "\n\n无论原文是什么语言，请全部使用%s撰写摘要。",
+		Filtered:   "（此部分内容被内容过滤拦截，未能生成摘要）",
+		Failed:     "（此部分摘要生成失败，请重新运行）",
+		Words:      "\n\n摘要的篇幅控制在约%d字。",
+		Glossary:   "\n\n以下是本视频涉及的专有名词和术语，转录中发音相近的写法应为这些术语，请在摘要中按此拼写并保持前后一致：%s",
+		Extract:    extractPrompt,
+		Extractors: map[Extractor]string{
+			ExtractTopics:   "视频讨论的关键主题 (每项为简短的短语，按重要性排列，不超过10项)",
+			ExtractEntities: "提到的命名实体，包括人物、组织、地点、产品和作品等，每项格式为“名称 (类型)”",
+			ExtractActions:  "行动事项，即视频中布置、建议或约定要做的具体事情；有负责人或期限时一并注明",
+		},
+		Presets: map[string]string{
+			"meeting":   "\n\n这是一次会议的记录。请整理为会议纪要：概括讨论的议题和各方观点，明确列出达成的决定，以及待办事项的负责人和期限；省略寒暄和与议题无关的闲聊。",
+			"lecture":   "\n\n这是一堂讲座或课程。请整理为学习笔记：保留讲授的概念、定义、公式、步骤和举例，体现知识点之间的逻辑关系，便于复习。",
+			"podcast":   "\n\n这是一期播客节目。请围绕讨论的主题整理主要观点、论据和有价值的见解，注明观点来自主持人还是嘉宾；省略广告和节目的开场与结尾。",
+			"interview": "\n\n这是一次访谈。请按问答的顺序整理：概括每个问题，记录受访者的回答要点，重要的原话可以用引号保留。",
+		},
+		Names: languageNames,
+	},
+	"en": {
+		System:     "You are a professional note-taking assistant who turns video transcripts into accurate, well-organized study notes. Write only from the content provided and never invent information; keep proper nouns, numbers and key conclusions; output the notes directly without any preamble or closing remarks.",
+		Summary:    englishPromptTemplate,
+		Flashcards: englishFlashcardsPromptTemplate,
+		Reduce:     englishReducePrompt,
+		Dedup:      englishDedupPrompt,
+		Expand:     englishExpandPrompt,
+		Condense:   englishCondensePrompt,
+		Part:       "[Part %d]",
+		Markdown:   "\n\nFormat the output as Markdown: use \"### \" subheadings to group the key points and \"- \" bullets for the details. Do not output level-1 or level-2 headings.",
+		Cards:      "\n\nOutput the cards strictly in the following format, separated by blank lines, and nothing else:\nQ: question\nA: answer",
+		Speakers:   "\n\n[Speaker N] labels in the transcript mark who is speaking; keep each point attributed to its speaker in the summary.",
+		Chapter:    "\n\nThis part belongs to the video chapter \"%s\".",
+		Context:    "\n\nThe following is the end of the previous part. Use it only as context and do not include it in this part's summary:\n",
+		Language:   "\n\nWrite the entire summary in %s, regardless of the language of the transcript.",
+		Filtered:   "(This part was blocked by the content filter and has no summary.)",
+		Failed:     "(The summary for this part failed to generate; please run again.)",
+		Words:      "\n\nKeep the summary to about %d words.",
+		Glossary:   "\n\nThe following names and terms appear in this video. Words in the transcript that sound like them refer to these terms; spell them exactly as listed and consistently throughout the summary: %s",
+		Extract:    englishExtractPrompt,
+		Extractors: map[Extractor]string{
+			ExtractTopics:   "the key topics discussed in the video (short phrases, most important first, at most 10)",
+			ExtractEntities: "the named entities mentioned, such as people, organizations, places, products and works, each formatted as \"name (type)\"",
+			ExtractActions:  "the action items, i.e. concrete tasks the video assigns, recommends or agrees on; include the owner or deadline when given",
+		},
+		Presets: map[string]string{
+			"meeting":   "\n\nThis is a meeting recording. Write it up as meeting minutes: summarize the topics discussed and each side's position, clearly list the decisions made and the action items with their owners and deadlines; leave out small talk and off-topic chatter.",
+			"lecture":   "\n\nThis is a lecture or course. Write study notes that keep the concepts, definitions, formulas, steps and examples taught, and show how the ideas connect so they are easy to review.",
+			"podcast":   "\n\nThis is a podcast episode. Organize the main points, arguments and notable insights by topic, noting whether each view comes from the host or a guest; leave out ads and the show's intro and outro.",
+			"interview": "\n\nThis is an interview. Follow the order of the questions: summarize each question and the key points of the answer, keeping important remarks as direct quotes.",
+		},
+		Names: englishLanguageNames,
+	},
+}
+
+// defaultPromptLanguage 为无法判断转录语言时使用的提示词语言
+const defaultPromptLanguage = "zh"
+
+// promptsFor 选择提示词语言：优先使用 -lang 指定的笔记语言，其次是转录内容的语言，
+// 都没有对应的内置提示词时使用中文
+func promptsFor(lang string, transcript *Transcript) promptSet {
+	for _, code := range []string{lang, transcript.language()} {
+		if set, ok := promptSets[strings.ToLower(code)]; ok {
+			return set
+		}
+	}
+	return promptSets[defaultPromptLanguage]
+}
+
+// formatHint 返回输出格式对应的要求
+func (p promptSet) formatHint(f Format) string {
+	switch {
+	case f == FormatMarkdown:
+		return p.Markdown
+	case f.isFlashcards():
+		return p.Cards
+	}
+	return ""
+}
+
+// languageHint 返回要求模型使用指定语言撰写摘要的提示词，lang 为空时不做要求
+func (p promptSet) languageHint(lang string) string {
+	if lang == "" {
+		return ""
+	}
+	name, ok := p.Names[strings.ToLower(lang)]
+	if !ok {
+		name = lang
+	}
+	return fmt.Sprintf(p.Language, name)
+}
+
+// promptData 为摘要提示词模板可使用的占位符
+type promptData struct {
+	// Text 为当前文本块的转录内容
+	Text string
+	// Ratio 为摘要比例，如 0.2
+	Ratio float64
+	// Percent 为百分比形式的摘要比例，如 20
+	Percent int
+	// Words 为 -words 分配给当前文本块的目标字数，未指定时为 0
+	Words int
+}
+
+// 校验模板时使用的占位文本，用于确认模板确实引用了 {{.Text}}
+const promptTextSentinel = "\x00video-note-text\x00"
+
+// template 返回输出格式对应的内置模板
+func (p promptSet) template(f Format) string {
+	if f.isFlashcards() {
+		return p.Flashcards
+	}
+	return p.Summary
+}
+
+// LoadPromptTemplate 读取并校验提示词模板，path 为空时返回 nil，由摘要时按语言选择内置模板
+func LoadPromptTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取提示词模板失败: %w", err)
+	}
+
+	tmpl, err := template.New(path).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("解析提示词模板失败: %w", err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, promptData{Text: promptTextSentinel, Ratio: 0.2, Percent: 20}); err != nil {
+		return nil, fmt.Errorf("提示词模板无效 (可用占位符: {{.Text}}, {{.Ratio}}, {{.Percent}}): %w", err)
+	}
+	if !strings.Contains(b.String(), promptTextSentinel) {
+		return nil, fmt.Errorf("提示词模板 %s 缺少占位符 {{.Text}}，无法插入转录内容", path)
+	}
+
+	return tmpl, nil
+}
+
+func buildPrompt(tmpl *template.Template, text string, ratio float64, words int) (string, error) {
+	var b strings.Builder
+	data := promptData{
+		Text:    text,
+		Ratio:   ratio,
+		Percent: int(math.Round(ratio * 100)),
+		Words:   words,
+	}
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("生成提示词失败: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,123 @@
+package videonote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Mode 表示多个文本块的摘要如何组成最终笔记
+type Mode string
+
+const (
+	// ModeFlat 逐块生成摘要后直接拼接
+	ModeFlat Mode = "flat"
+	// ModeMapReduce 逐块生成摘要后再整合为一份连贯的笔记
+	ModeMapReduce Mode = "map-reduce"
+)
+
+func ParseMode(s string) (Mode, error) {
+	switch Mode(strings.ToLower(s)) {
+	case ModeFlat:
+		return ModeFlat, nil
+	case ModeMapReduce, "mapreduce":
+		return ModeMapReduce, nil
+	default:
+		return "", fmt.Errorf("不支持的摘要模式: %s (可选: flat, map-reduce)", s)
+	}
+}
+
+// 单次整合请求中各部分摘要的总长度上限（字节）
+const reduceBudget = 6000
+
+const reducePrompt = `以下是同一个视频按时间顺序分段生成的笔记摘要。请将它们整合为一份连贯、完整的笔记：
+- 合并各部分之间重复或相近的要点
+- 按主题重新组织内容，而不是按分段罗列
+- 保留关键信息和重要细节
+
+%s`
+
+const englishReducePrompt = `The following are notes generated, in chronological order, for consecutive parts of the same video. Merge them into one coherent, complete set of notes:
+- Combine points that are repeated or overlap between parts
+- Reorganize the content by topic instead of listing it part by part
+- Keep the key information and important details
+
+%s`
+
+// reduceSummaries 将各部分摘要整合为一份笔记；合并后的内容超过 reduceBudget 时
+// 先分组整合，再对整合结果递归处理，直到可以一次完成
+func reduceSummaries(ctx context.Context, summarizer Summarizer, limiter *rateLimiter, config *Config, summaries []string, opts SummarizeOptions) (string, error) {
+	for {
+		groups := groupTexts(summaries, reduceBudget)
+		// 只剩一组，或每个摘要单独都超出上限无法再分组时，直接整合全部内容
+		if len(groups) == 1 || len(groups) == len(summaries) {
+			return reduceOnce(ctx, summarizer, limiter, config, summaries, opts.Words, opts)
+		}
+
+		next := make([]string, len(groups))
+		for i, group := range groups {
+			summary, err := reduceOnce(ctx, summarizer, limiter, config, group, 0, opts)
+			if err != nil {
+				return "", fmt.Errorf("整合第%d组摘要失败: %w", i+1, err)
+			}
+			next[i] = summary
+		}
+		summaries = next
+	}
+}
+
+// reduceOnce 整合一组摘要；words 大于 0 时按该字数限制整合结果，只用于最后一次整合
+func reduceOnce(ctx context.Context, summarizer Summarizer, limiter *rateLimiter, config *Config, summaries []string, words int, opts SummarizeOptions) (string, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+
+	parts := make([]string, len(summaries))
+	for i, summary := range summaries {
+		parts[i] = fmt.Sprintf(opts.prompts.Part, i+1) + "\n" + strings.TrimSpace(summary)
+	}
+
+	joined := strings.Join(parts, "\n\n")
+	prompt := fmt.Sprintf(opts.prompts.Reduce, joined) + opts.prompts.presetHint(opts.Preset) +
+		opts.prompts.formatHint(opts.Format) + opts.prompts.languageHint(opts.Language) + opts.prompts.glossaryHint(opts.Glossary)
+	maxTokens := 0
+	if words > 0 {
+		prompt += fmt.Sprintf(opts.prompts.Words, words)
+		count := tokenCounter(config.SummarizeModel)
+		maxTokens = completionTokens(config.SummarizeModel, count(prompt), count(joined), float64(words)/float64(max(textLength(joined), 1)))
+	}
+	var stream io.Writer
+	if opts.Stream {
+		stream = os.Stderr
+		fmt.Fprintln(stream, "\n--- 整合摘要 ---")
+	}
+	result, err := summarizer.Complete(ctx, prompt, maxTokens, stream)
+	if err != nil {
+		return "", err
+	}
+	if result.Model != config.SummarizeModel {
+		infof("整合摘要由备用模型 %s 生成", result.Model)
+	}
+	return result.Text, nil
+}
+
+// groupTexts 按顺序将文本分组，使每组的总长度不超过 budget（单个超长文本独占一组）
+func groupTexts(texts []string, budget int) [][]string {
+	var groups [][]string
+	var current []string
+	size := 0
+	for _, text := range texts {
+		if len(current) > 0 && size+len(text) > budget {
+			groups = append(groups, current)
+			current, size = nil, 0
+		}
+		current = append(current, text)
+		size += len(text)
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups
+}
@@ -0,0 +1,78 @@
+package videonote
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DefaultRatioTolerance 为 -refine 允许的摘要长度偏差，0.25 表示目标长度的 ±25%
+const DefaultRatioTolerance = 0.25
+
+// 目标长度过短时模型难以精确控制，不再调整
+const minRefineTarget = 50
+
+const expandPrompt = `以下是一段视频转录及根据它生成的笔记摘要。摘要偏短，请参考转录内容补充重要的细节，将摘要扩写到约 %d 字。不要编造转录中没有的内容，保持原有的格式，只输出扩写后的摘要。
+
+转录：
+%s
+
+摘要：
+%s`
+
+const englishExpandPrompt = `The following is a video transcript and the notes generated from it. The notes are too short: using the transcript, add important details and expand the notes to about %d words. Do not invent anything that is not in the transcript, keep the original formatting, and output only the expanded notes.
+
+Transcript:
+%s
+
+Notes:
+%s`
+
+const condensePrompt = `以下笔记摘要偏长，请将它精简到约 %d 字，保留最重要的信息，保持原有的格式，只输出精简后的摘要：
+
+%s`
+
+const englishCondensePrompt = `The following notes are too long. Condense them to about %d words, keeping the most important information and the original formatting, and output only the condensed notes:
+
+%s`
+
+// refineLength 测量摘要的实际长度，偏离 source 按 ratio 计算的目标长度超过容差时，
+// 请求模型扩写或精简，最多调整 opts.Refine 次
+func refineLength(ctx context.Context, summarizer Summarizer, limiter *rateLimiter, summary, source string, ratio float64, opts SummarizeOptions) (string, error) {
+	target := int(float64(textLength(source)) * ratio)
+	if target < minRefineTarget {
+		return summary, nil
+	}
+	tolerance := opts.RatioTolerance
+	if tolerance <= 0 {
+		tolerance = DefaultRatioTolerance
+	}
+
+	for i := 0; i < opts.Refine; i++ {
+		length := textLength(summary)
+		deviation := float64(length-target) / float64(target)
+		if math.Abs(deviation) <= tolerance {
+			break
+		}
+
+		var prompt string
+		if deviation < 0 {
+			prompt = fmt.Sprintf(opts.prompts.Expand, target, strings.TrimSpace(source), strings.TrimSpace(summary))
+		} else {
+			prompt = fmt.Sprintf(opts.prompts.Condense, target, strings.TrimSpace(summary))
+		}
+		prompt += opts.prompts.formatHint(opts.Format) + opts.prompts.languageHint(opts.Language) + opts.prompts.glossaryHint(opts.Glossary)
+		debugf("摘要长度 %d，目标 %d，第%d次调整", length, target, i+1)
+
+		if err := limiter.Wait(ctx); err != nil {
+			return "", err
+		}
+		result, err := summarizer.Complete(ctx, prompt, 0, nil)
+		if err != nil {
+			return "", fmt.Errorf("调整摘要长度失败: %w", err)
+		}
+		summary = result.Text
+	}
+	return summary, nil
+}
@@ -0,0 +1,95 @@
+package videonote
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultMaxAttempts = 4
+	retryBaseDelay     = time.Second
+	retryMaxDelay      = 30 * time.Second
+)
+
+// withRetry 执行 fn，遇到限流或服务端错误时按指数退避加随机抖动重试，
+// 最多尝试 maxAttempts 次；其余错误（如鉴权失败）立即返回
+func withRetry(ctx context.Context, maxAttempts int, fn func() error) error {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxAttempts || !isRetryable(err) {
+			return err
+		}
+
+		// 在 [delay/2, delay) 范围内随机等待，避免并发请求同时重试
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+		warnf("请求失败，%v后进行第%d次重试: %v", wait.Round(time.Millisecond), attempt, err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// countCalls 包装调用 OpenAI 接口的 fn，每次尝试都计入 -report 的接口调用次数，第二次起计为重试；
+// webhook 等其他请求同样使用 withRetry，但不计入接口调用
+func countCalls(fn func() error) func() error {
+	attempt := 0
+	return func() error {
+		attempt++
+		metrics.addCall(attempt > 1)
+		return fn()
+	}
+}
+
+// httpStatusError 为直接发送的 HTTP 请求 (如 webhook) 的失败，StatusCode 为 0 表示没有收到响应
+type httpStatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *httpStatusError) Error() string { return e.Err.Error() }
+func (e *httpStatusError) Unwrap() error { return e.Err }
+
+func isRetryable(err error) bool {
+	// 连接失败或单次请求超时时接收方可能暂时不可用，先于下面的 context 判断
+	var httpErr *httpStatusError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == 0 || isRetryableStatus(httpErr.StatusCode)
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return isRetryableStatus(apiErr.HTTPStatusCode)
+	}
+
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return isRetryableStatus(reqErr.HTTPStatusCode)
+	}
+
+	return false
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
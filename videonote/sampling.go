@@ -0,0 +1,81 @@
+package videonote
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// 未配置 temperature 时使用的默认值
+const defaultTemperature = 0.3
+
+func validateTemperature(v float32) error {
+	if v < 0 || v > 2 {
+		return fmt.Errorf("temperature 必须在 0 到 2 之间，当前为 %g", v)
+	}
+	return nil
+}
+
+func validateTopP(v float32) error {
+	if v <= 0 || v > 1 {
+		return fmt.Errorf("top_p 必须大于 0 且不超过 1，当前为 %g", v)
+	}
+	return nil
+}
+
+// RegisterSamplingFlags 注册 -temperature、-top-p 和 -max-tokens，设置后覆盖配置文件中的值
+func RegisterSamplingFlags(fs *flag.FlagSet, config *Config) {
+	fs.Func("temperature", fmt.Sprintf("生成摘要的 temperature (0-2，默认: %g)", defaultTemperature), func(s string) error {
+		v, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return fmt.Errorf("无效的数值: %s", s)
+		}
+		if err := validateTemperature(float32(v)); err != nil {
+			return err
+		}
+		t := float32(v)
+		config.Temperature = &t
+		return nil
+	})
+	fs.Func("top-p", "生成摘要的 top_p (0-1，默认使用模型默认值)", func(s string) error {
+		v, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return fmt.Errorf("无效的数值: %s", s)
+		}
+		if err := validateTopP(float32(v)); err != nil {
+			return err
+		}
+		p := float32(v)
+		config.TopP = &p
+		return nil
+	})
+	fs.Func("max-tokens", "每次摘要请求的输出token上限 (默认按摘要比例自动计算)", func(s string) error {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			return fmt.Errorf("无效的token数: %s", s)
+		}
+		config.MaxTokens = v
+		return nil
+	})
+}
+
+// applySampling 将采样参数写入请求；maxTokens 为按摘要比例计算的上限，配置了 max_tokens 时以配置为准
+func (c *Config) applySampling(req *openai.ChatCompletionRequest, maxTokens int) {
+	if c.Temperature != nil {
+		req.Temperature = *c.Temperature
+		// temperature 字段为 omitempty，0 会被省略而使用接口默认值 1
+		if req.Temperature == 0 {
+			req.Temperature = math.SmallestNonzeroFloat32
+		}
+	}
+	if c.TopP != nil {
+		req.TopP = *c.TopP
+	}
+	req.MaxTokens = maxTokens
+	if c.MaxTokens > 0 {
+		req.MaxTokens = c.MaxTokens
+	}
+}
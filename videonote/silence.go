@@ -0,0 +1,107 @@
+package videonote
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// interval 为媒体中的一段时间区间
+type interval struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+var (
+	silenceStartRe = regexp.MustCompile(`silence_start: (-?[\d.]+)`)
+	silenceEndRe   = regexp.MustCompile(`silence_end: ([\d.]+)`)
+)
+
+// detectSilence 使用 ffmpeg 的 silencedetect 滤镜找出静音区间；末尾未结束的静音以 +Inf 结束
+func detectSilence(ctx context.Context, config *Config, mediaPath string, opts ExtractOptions) ([]interval, error) {
+	filter := fmt.Sprintf("silencedetect=noise=%s:d=%s", opts.SilenceThreshold, formatSeconds(opts.SilenceDuration))
+	args := append(opts.rangeArgs(), "-i", mediaPath, "-vn")
+	args = append(args, opts.mapArgs()...)
+	args = append(args, "-af", filter, "-f", "null", "-")
+	cmd := exec.CommandContext(ctx, config.ffmpegBinary(), args...)
+	debugCommand(cmd)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("检测静音被中断: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("检测静音失败: %w", ffmpegError("ffmpeg", mediaPath, err, output))
+	}
+
+	var silences []interval
+	var start *time.Duration
+	for _, line := range strings.Split(string(output), "\n") {
+		if m := silenceStartRe.FindStringSubmatch(line); m != nil {
+			seconds, _ := strconv.ParseFloat(m[1], 64)
+			s := secondsToDuration(max(seconds, 0))
+			start = &s
+		} else if m := silenceEndRe.FindStringSubmatch(line); m != nil && start != nil {
+			seconds, _ := strconv.ParseFloat(m[1], 64)
+			silences = append(silences, interval{Start: *start, End: secondsToDuration(seconds)})
+			start = nil
+		}
+	}
+	if start != nil {
+		silences = append(silences, interval{Start: *start, End: time.Duration(math.MaxInt64)})
+	}
+	return silences, nil
+}
+
+// silenceFilter 生成去除指定静音区间的音频滤镜
+func silenceFilter(silences []interval) string {
+	terms := make([]string, len(silences))
+	for i, s := range silences {
+		end := "1e9"
+		if s.End != time.Duration(math.MaxInt64) {
+			end = formatSeconds(s.End)
+		}
+		terms[i] = fmt.Sprintf("between(t,%s,%s)", formatSeconds(s.Start), end)
+	}
+	return fmt.Sprintf("aselect='not(%s)',asetpts=N/SR/TB", strings.Join(terms, "+"))
+}
+
+// timeMap 记录去除静音时删掉的区间和 -start 截取的起点，用于把处理后音频中的时间换算回原视频的时间
+type timeMap struct {
+	removed []interval
+	offset  time.Duration
+}
+
+// original 将去除静音后音频中的时间 t 换算为原视频中的时间
+func (m *timeMap) original(t time.Duration) time.Duration {
+	if m == nil {
+		return t
+	}
+	var removed time.Duration
+	for _, s := range m.removed {
+		// s.Start-removed 为该静音区间在处理后音频中的位置
+		if t+removed < s.Start {
+			break
+		}
+		if s.End == time.Duration(math.MaxInt64) {
+			break
+		}
+		removed += s.End - s.Start
+	}
+	return t + removed + m.offset
+}
+
+// apply 将转录片段的时间戳换算回原视频时间
+func (m *timeMap) apply(segments []Segment) {
+	if m == nil {
+		return
+	}
+	for i := range segments {
+		segments[i].Start = m.original(segments[i].Start)
+		segments[i].End = m.original(segments[i].End)
+	}
+}
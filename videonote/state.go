@@ -0,0 +1,212 @@
+package videonote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 批量处理状态文件的默认路径，位于当前目录
+const DefaultStateFile = ".video-note-state.json"
+
+// 计算输入指纹时读取的首尾字节数，避免为大视频读取整个文件
+const fingerprintSample = 1 << 20
+
+// BatchState 记录批量处理中已完成的输入，中断后重新运行时跳过这些输入；
+// 输入被修改 (指纹变化) 或笔记被删除时会重新处理
+type BatchState struct {
+	path  string
+	force bool
+
+	mu    sync.Mutex
+	Files map[string]stateEntry `json:"files"`
+	// LastRun 为上一次全部成功的批量处理的开始时间，供 -since last 使用
+	LastRun time.Time `json:"last_run,omitempty"`
+}
+
+// stateEntry 为一个已完成输入的记录
+type stateEntry struct {
+	Fingerprint string    `json:"fingerprint"`
+	Output      string    `json:"output"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// LoadBatchState 读取状态文件，文件不存在时返回空状态；force 为 true 时不跳过任何输入，
+// 但仍会记录本次完成的输入
+func LoadBatchState(path string, force bool) (*BatchState, error) {
+	state := &BatchState{path: path, force: force, Files: map[string]stateEntry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取状态文件失败: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("解析状态文件 %s 失败，可删除该文件后重新运行: %w", path, err)
+	}
+	if state.Files == nil {
+		state.Files = map[string]stateEntry{}
+	}
+	return state, nil
+}
+
+// stateKey 返回输入在状态文件中的键，本地文件使用绝对路径，链接保持原样
+func stateKey(input string) string {
+	if isURL(input) {
+		return input
+	}
+	if abs, err := filepath.Abs(input); err == nil {
+		return abs
+	}
+	return input
+}
+
+// Done 判断输入是否已在之前的运行中完成，且输入未被修改、笔记仍然存在；
+// output 为空时 (如在线视频的默认位置) 使用上次记录的笔记路径
+func (s *BatchState) Done(input, output string) bool {
+	if s.force {
+		return false
+	}
+	s.mu.Lock()
+	entry, ok := s.Files[stateKey(input)]
+	s.mu.Unlock()
+	if !ok || (output != "" && entry.Output != output) {
+		return false
+	}
+	if entry.Output != "" {
+		if _, err := os.Stat(entry.Output); err != nil {
+			return false
+		}
+	}
+	fingerprint, err := inputFingerprint(input)
+	return err == nil && fingerprint == entry.Fingerprint
+}
+
+// Complete 记录输入已完成并立即写入状态文件，进程随时中断也不会丢失已完成的记录
+func (s *BatchState) Complete(input, output string) error {
+	fingerprint, err := inputFingerprint(input)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Files[stateKey(input)] = stateEntry{
+		Fingerprint: fingerprint,
+		Output:      output,
+		CompletedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	return s.save()
+}
+
+// Since 返回上一次全部成功的批量处理的开始时间，从未记录时为零值
+func (s *BatchState) Since() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.LastRun
+}
+
+// FinishRun 记录本次批量处理全部成功，start 为开始展开输入的时间；
+// 运行期间新增或修改的文件晚于 start，下次仍会处理
+func (s *BatchState) FinishRun(start time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.LastRun = start.UTC().Truncate(time.Second)
+	return s.save()
+}
+
+// save 写入状态文件，调用方需持有 s.mu
+func (s *BatchState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名，避免中断时留下不完整的状态文件
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// inputFingerprint 按文件大小、修改时间和首尾内容计算输入指纹，链接以自身作为指纹
+func inputFingerprint(input string) (string, error) {
+	if isURL(input) {
+		return input, nil
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		return "", fmt.Errorf("打开输入文件失败: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("读取输入文件信息失败: %w", err)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "size=%d\x00mtime=%d\x00", info.Size(), info.ModTime().UnixNano())
+	if _, err := io.CopyN(h, f, fingerprintSample); err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("读取输入文件失败: %w", err)
+	}
+	if info.Size() > 2*fingerprintSample {
+		if _, err := f.Seek(-fingerprintSample, io.SeekEnd); err != nil {
+			return "", fmt.Errorf("读取输入文件失败: %w", err)
+		}
+		if _, err := io.Copy(h, f); err != nil {
+			return "", fmt.Errorf("读取输入文件失败: %w", err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SinceLast 作为 -since 时使用状态文件中记录的上一次运行时间
+const SinceLast = "last"
+
+// ParseSince 解析 -since：日期 (如 2024-05-01，按本地时间)、RFC 3339 时间，或相对于 now 的时长 (如 24h)
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("无效的 -since: %s (可选: last、日期如 2024-05-01、RFC 3339 时间或时长如 24h)", s)
+}
+
+// ModifiedSince 返回修改时间晚于 since 的输入序号；在线链接没有修改时间，总是保留
+func ModifiedSince(inputs []string, since time.Time) []int {
+	var kept []int
+	for i, input := range inputs {
+		if !isURL(input) {
+			info, err := os.Stat(input)
+			if err == nil && !info.ModTime().After(since) {
+				continue
+			}
+		}
+		kept = append(kept, i)
+	}
+	return kept
+}
@@ -0,0 +1,94 @@
+package videonote
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+	"unicode"
+)
+
+// 估算阅读时间使用的阅读速度
+const (
+	readingCharsPerMinute = 300 // 中日韩文字，每分钟字数
+	readingWordsPerMinute = 200 // 其他文字，每分钟词数
+)
+
+// countText 统计文本长度：中日韩文字按字计，其他文字按空白分隔的词计
+func countText(s string) (chars, words int) {
+	inWord := false
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			chars++
+			inWord = false
+		case unicode.IsSpace(r) || unicode.IsPunct(r):
+			inWord = false
+		default:
+			if !inWord {
+				words++
+				inWord = true
+			}
+		}
+	}
+	return chars, words
+}
+
+// textLength 返回文本的字数，中文的字与英文的词各计为 1
+func textLength(s string) int {
+	chars, words := countText(s)
+	return chars + words
+}
+
+// readingMinutes 按阅读速度估算阅读文本所需的分钟数
+func readingMinutes(s string) float64 {
+	chars, words := countText(s)
+	return float64(chars)/readingCharsPerMinute + float64(words)/readingWordsPerMinute
+}
+
+// printStats 输出各部分原文与摘要的长度、实际摘要比例和预计阅读时间
+func printStats(w io.Writer, sections []Section, ratio float64) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "部分\t原文字数\t摘要字数\t比例\t")
+
+	var source, summary int
+	var minutes float64
+	for i, section := range sections {
+		length := textLength(section.Summary)
+		source += section.SourceLength
+		summary += length
+		minutes += readingMinutes(section.Summary)
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t\n", i+1, section.SourceLength, length, formatRatio(length, section.SourceLength))
+	}
+	if len(sections) > 1 {
+		fmt.Fprintf(tw, "合计\t%d\t%d\t%s\t\n", source, summary, formatRatio(summary, source))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "预计阅读时间: %d分钟\n", int(math.Max(1, math.Ceil(minutes))))
+
+	// 实际比例与要求相差一半以上时提示，可能需要调整提示词
+	if source > 0 {
+		achieved := float64(summary) / float64(source)
+		if achieved > ratio*1.5 || achieved < ratio*0.5 {
+			fmt.Fprintf(w, "注意: 实际摘要比例 %.1f%% 与要求的 %.0f%% 相差较大，可尝试调整 -ratio 或 -prompt-file\n",
+				achieved*100, ratio*100)
+		}
+	}
+}
+
+func formatRatio(part, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(part)/float64(total)*100)
+}
+
+// sourceLength 统计各部分原文字数之和
+func sourceLength(sections []Section) int {
+	total := 0
+	for _, section := range sections {
+		total += section.SourceLength
+	}
+	return total
+}
@@ -0,0 +1,207 @@
+package videonote
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 字幕每行的最大字符数，超出时折行为多行字幕
+const maxCueLineLength = 42
+
+func renderSRT(segments []Segment) string {
+	var b strings.Builder
+	for i, seg := range segments {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1,
+			formatSubtitleTime(seg.Start, ','), formatSubtitleTime(seg.End, ','),
+			strings.Join(wrapCueText(speakerPrefix(seg)+seg.Text), "\n"))
+	}
+	return b.String()
+}
+
+func renderVTT(segments []Segment) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, seg := range segments {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n",
+			formatSubtitleTime(seg.Start, '.'), formatSubtitleTime(seg.End, '.'),
+			voiceTag(seg)+strings.Join(wrapCueText(escapeVTT(seg.Text)), "\n"))
+	}
+	return b.String()
+}
+
+func speakerPrefix(seg Segment) string {
+	if seg.Speaker == "" {
+		return ""
+	}
+	return "[" + seg.Speaker + "] "
+}
+
+// voiceTag 使用 WebVTT 的 <v> 标签标注说话人
+func voiceTag(seg Segment) string {
+	if seg.Speaker == "" {
+		return ""
+	}
+	return "<v " + seg.Speaker + ">"
+}
+
+// parseSubtitles 解析 SRT 或 WebVTT 字幕为片段，忽略序号、样式标签和 WebVTT 的头部与注释
+func parseSubtitles(text string) ([]Segment, error) {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\ufeff", "")
+	var segments []Segment
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		for i, line := range lines {
+			before, after, ok := strings.Cut(line, "-->")
+			if !ok {
+				continue
+			}
+			start, err := parseSubtitleTime(before)
+			if err != nil {
+				return nil, err
+			}
+			// WebVTT 的时间后面可能跟着位置等设置
+			fields := strings.Fields(after)
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("字幕时间行缺少结束时间: %s", line)
+			}
+			end, err := parseSubtitleTime(fields[0])
+			if err != nil {
+				return nil, err
+			}
+			cue := strings.TrimSpace(stripCueTags(strings.Join(lines[i+1:], " ")))
+			if cue != "" {
+				segments = append(segments, Segment{Start: start, End: end, Text: cue})
+			}
+			break
+		}
+	}
+	return segments, nil
+}
+
+// srtStartRe 匹配 SRT 开头的序号和第一条时间行
+var srtStartRe = regexp.MustCompile(`^\d+\s*\n\s*\d{1,2}:\d{2}:\d{2}[,.]\d{3}\s*-->`)
+
+// looksLikeSubtitles 判断文本是否为 SRT 或 WebVTT 字幕
+func looksLikeSubtitles(text string) bool {
+	text = strings.TrimSpace(strings.ReplaceAll(strings.TrimPrefix(text, "\ufeff"), "\r\n", "\n"))
+	return strings.HasPrefix(text, "WEBVTT") || srtStartRe.MatchString(text)
+}
+
+// ParseTranscriptText 将输入的文本转为转录：字幕保留每条字幕的时间戳，其余按纯文本处理
+func ParseTranscriptText(text string) (*Transcript, error) {
+	if !looksLikeSubtitles(text) {
+		return &Transcript{Text: text}, nil
+	}
+	return subtitleTranscript(text)
+}
+
+// subtitleTranscript 将字幕解析为带时间戳的转录。YouTube 自动字幕的每条字幕会重复上一条的内容
+// 以实现滚动显示，合并时去掉重复的部分
+func subtitleTranscript(text string) (*Transcript, error) {
+	cues, err := parseSubtitles(text)
+	if err != nil {
+		return nil, fmt.Errorf("解析字幕失败: %w", err)
+	}
+
+	var segments []Segment
+	for _, cue := range cues {
+		if n := len(segments); n > 0 {
+			prev := &segments[n-1]
+			if cue.Text == prev.Text {
+				prev.End = max(prev.End, cue.End)
+				continue
+			}
+			if rest, ok := strings.CutPrefix(cue.Text, prev.Text); ok {
+				cue.Text = strings.TrimSpace(rest)
+			}
+		}
+		if cue.Text != "" {
+			segments = append(segments, cue)
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("字幕中没有文字内容")
+	}
+
+	t := &Transcript{Segments: segments}
+	t.Text = t.joinSegments()
+	return t, nil
+}
+
+// parseSubtitleTime 解析 HH:MM:SS,mmm、HH:MM:SS.mmm 或 WebVTT 中省略小时的 MM:SS.mmm
+func parseSubtitleTime(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	var h, m, sec, ms int
+	normalized := strings.Replace(s, ",", ".", 1)
+	if strings.Count(normalized, ":") == 1 {
+		normalized = "00:" + normalized
+	}
+	if _, err := fmt.Sscanf(normalized, "%d:%d:%d.%d", &h, &m, &sec, &ms); err != nil {
+		return 0, fmt.Errorf("无效的字幕时间: %q", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(sec)*time.Second + time.Duration(ms)*time.Millisecond, nil
+}
+
+// stripCueTags 去除字幕文本中的 <v Speaker>、<i> 等标签并还原转义字符
+func stripCueTags(text string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range text {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>' && inTag:
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&").Replace(b.String())
+}
+
+// formatSubtitleTime 格式化为 HH:MM:SS,mmm (SRT) 或 HH:MM:SS.mmm (VTT)
+func formatSubtitleTime(d time.Duration, sep byte) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// escapeVTT 转义 WebVTT 字幕文本中具有特殊含义的字符
+func escapeVTT(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// wrapCueText 将字幕文本折行，保证每行不超过 maxCueLineLength 个字符；
+// 中文等没有空格分隔的文本按字符数截断
+func wrapCueText(text string) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		for utf8.RuneCountInString(word) > maxCueLineLength {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:maxCueLineLength]))
+			word = string(runes[maxCueLineLength:])
+		}
+
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > maxCueLineLength:
+			lines = append(lines, line)
+			line = word
+		default:
+			line += " " + word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
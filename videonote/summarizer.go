@@ -0,0 +1,222 @@
+package videonote
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// 可选的摘要后端
+const summarizerOpenAI = "openai"
+
+// Summarizer 根据提示词生成摘要。分块、并发、整合等逻辑与具体服务无关，
+// 新增后端时只需实现该接口并在 newSummarizer 中注册；
+// stream 不为 nil 时应将生成中的内容实时写入 stream
+type Summarizer interface {
+	Complete(ctx context.Context, prompt string, maxTokens int, stream io.Writer) (Completion, error)
+}
+
+// Completion 为一次生成的结果
+type Completion struct {
+	Text string
+	// Model 为实际生成该结果的模型，主模型不可用时为备用模型
+	Model string
+}
+
+// systemPromptNone 作为 system_prompt 时不发送系统消息
+const systemPromptNone = "none"
+
+// newSummarizer 按配置中的 summarizer 创建摘要后端，system 为配置未指定时使用的默认系统消息
+func newSummarizer(config *Config, system string) (Summarizer, error) {
+	switch config.SystemPrompt {
+	case "":
+	case systemPromptNone:
+		system = ""
+	default:
+		system = config.SystemPrompt
+	}
+
+	switch config.Summarizer {
+	case "", summarizerOpenAI:
+		return &OpenAISummarizer{config: config, client: newOpenAIClient(config), system: system}, nil
+	default:
+		return nil, fmt.Errorf("不支持的摘要后端: %s (可选: openai)", config.Summarizer)
+	}
+}
+
+var (
+	// errEmptyResponse 表示接口没有返回任何内容
+	errEmptyResponse = errors.New("接口返回了空的回复")
+	// errContentFiltered 表示回复被服务端的内容过滤拦截
+	errContentFiltered = errors.New("回复被内容过滤拦截")
+)
+
+// OpenAISummarizer 调用 OpenAI 兼容的对话接口生成摘要，也适用于 Azure 和 base_url 指向的兼容服务
+type OpenAISummarizer struct {
+	config *Config
+	client *openai.Client
+	// system 不为空时作为系统消息随每个请求发送
+	system string
+}
+
+// Complete 依次尝试 summarize_model 和 fallback_models，前一个模型在重试后仍限流或
+// 出现服务端错误时改用下一个；鉴权失败、内容过滤等错误不会切换模型
+func (s *OpenAISummarizer) Complete(ctx context.Context, prompt string, maxTokens int, stream io.Writer) (Completion, error) {
+	models := s.config.summarizeModels()
+	for i, model := range models {
+		text, err := s.completeWith(ctx, model, prompt, maxTokens, stream)
+		if err == nil {
+			return Completion{Text: text, Model: model}, nil
+		}
+		if i == len(models)-1 || !isRetryable(err) {
+			return Completion{}, err
+		}
+		warnf("模型 %s 暂时不可用，改用 %s: %v", model, models[i+1], err)
+	}
+	return Completion{}, fmt.Errorf("未配置摘要模型")
+}
+
+// completeWith 使用指定模型发送单轮对话请求，遇到限流或服务端错误时自动重试
+func (s *OpenAISummarizer) completeWith(ctx context.Context, model, prompt string, maxTokens int, stream io.Writer) (string, error) {
+	config, client := s.config, s.client
+	var messages []openai.ChatCompletionMessage
+	if s.system != "" {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: s.system,
+		})
+	}
+	req := openai.ChatCompletionRequest{
+		Model: model,
+		Messages: append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: prompt,
+		}),
+	}
+	config.applySampling(&req, maxTokens)
+
+	if stream != nil {
+		return s.completeStream(ctx, req, stream)
+	}
+
+	var resp openai.ChatCompletionResponse
+	start := time.Now()
+	err := withRetry(ctx, config.MaxAttempts, countCalls(func() (err error) {
+		resp, err = client.CreateChatCompletion(ctx, req)
+		return err
+	}))
+	if err != nil {
+		return "", err
+	}
+	addUsage(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	debugf("摘要请求完成 (%s)，耗时 %v，输入 %d tokens，输出 %d tokens",
+		model, time.Since(start).Round(time.Millisecond), resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+
+	if len(resp.Choices) == 0 {
+		return "", errEmptyResponse
+	}
+	choice := resp.Choices[0]
+	if choice.FinishReason == openai.FinishReasonContentFilter {
+		return "", errContentFiltered
+	}
+	// 模型拒绝回答时 Refusal 为拒绝的理由，按内容过滤处理
+	if choice.Message.Refusal != "" {
+		return "", fmt.Errorf("%w: %s", errContentFiltered, choice.Message.Refusal)
+	}
+	if strings.TrimSpace(choice.Message.Content) == "" {
+		return "", fmt.Errorf("%w (finish_reason=%s)", errEmptyResponse, choice.FinishReason)
+	}
+	return choice.Message.Content, nil
+}
+
+func (s *OpenAISummarizer) completeStream(ctx context.Context, req openai.ChatCompletionRequest, stream io.Writer) (string, error) {
+	req.Stream = true
+	// 流式响应默认不含用量，要求在最后一个数据块中返回
+	req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	var content strings.Builder
+	var used *openai.Usage
+	err := withRetry(ctx, s.config.MaxAttempts, countCalls(func() error {
+		// 重试时丢弃上一次已收到的部分内容
+		if content.Len() > 0 {
+			fmt.Fprintln(stream, "\n[连接中断，重新生成...]")
+			content.Reset()
+		}
+		used = nil
+
+		r, err := s.client.CreateChatCompletionStream(ctx, req)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		for {
+			resp, err := r.Recv()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("接收流式响应失败: %w", err)
+			}
+			if resp.Usage != nil {
+				used = resp.Usage
+			}
+			if len(resp.Choices) == 0 {
+				continue
+			}
+			if resp.Choices[0].FinishReason == openai.FinishReasonContentFilter || resp.Choices[0].Delta.Refusal != "" {
+				return errContentFiltered
+			}
+			delta := resp.Choices[0].Delta.Content
+			content.WriteString(delta)
+			io.WriteString(stream, delta)
+		}
+	}))
+	if err != nil {
+		return "", err
+	}
+	if used != nil {
+		addUsage(ctx, used.PromptTokens, used.CompletionTokens)
+	}
+
+	fmt.Fprintln(stream)
+	if strings.TrimSpace(content.String()) == "" {
+		return "", errEmptyResponse
+	}
+	return content.String(), nil
+}
+
+// summarizeModels 返回按顺序尝试的摘要模型
+func (c *Config) summarizeModels() []string {
+	return append([]string{c.SummarizeModel}, c.FallbackModels...)
+}
+
+// RegisterModelsFlag 注册 -models，逗号分隔的第一个模型为主模型，其余为备用模型，
+// 设置后覆盖配置文件中的 summarize_model 和 fallback_models
+func RegisterModelsFlag(fs *flag.FlagSet, config *Config) {
+	fs.Func("models", "逗号分隔的摘要模型，依次作为主模型和备用模型，如 gpt-4o,gpt-4o-mini", func(s string) error {
+		var models []string
+		for _, model := range strings.Split(s, ",") {
+			if model = strings.TrimSpace(model); model != "" {
+				models = append(models, model)
+			}
+		}
+		if len(models) == 0 {
+			return fmt.Errorf("至少需要指定一个模型")
+		}
+		config.SummarizeModel = models[0]
+		config.FallbackModels = models[1:]
+		return nil
+	})
+}
+
+// RegisterSystemPromptFlag 注册 -system-prompt，设置后覆盖配置文件中的 system_prompt
+func RegisterSystemPromptFlag(fs *flag.FlagSet, config *Config) {
+	fs.StringVar(&config.SystemPrompt, "system-prompt", config.SystemPrompt, "摘要请求的系统消息，设定助手的角色和输出规范 (默认使用内置的笔记助手设定，none 表示不发送)")
+}
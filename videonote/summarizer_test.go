@@ -0,0 +1,116 @@
+package videonote
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSummarizer 按提示词中的句子编号生成确定的摘要，并记录收到的提示词
+type fakeSummarizer struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (f *fakeSummarizer) Complete(ctx context.Context, prompt string, maxTokens int, stream io.Writer) (Completion, error) {
+	f.mu.Lock()
+	f.prompts = append(f.prompts, prompt)
+	f.mu.Unlock()
+	return Completion{Text: echoSentences(prompt), Model: "fake"}, nil
+}
+
+// 注入模拟的转录和摘要后端，不经过 OpenAI 接口检查分块、逐块摘要和整合的结果
+func TestGenerateWithSummarizeEngine(t *testing.T) {
+	const sentences = 30
+	tests := []struct {
+		mode Mode
+		// wantReduce 为整合请求的数量
+		wantReduce int
+	}{
+		{ModeFlat, 0},
+		{ModeMapReduce, 1},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			// 没有 base_url 和 API Key，任何真实的接口调用都会失败
+			config := &Config{
+				TranscribeModel: "whisper-1",
+				SummarizeModel:  "gpt-4o-mini",
+				ChunkTokens:     200,
+				FFmpegPath:      filepath.Join(t.TempDir(), "ffmpeg"),
+			}
+			summarizer := &fakeSummarizer{}
+			opts := testGenerateOptions()
+			opts.Mode = tt.mode
+			opts.TranscribeEngine = &fakeTranscriber{transcript: Transcript{Text: numberedTranscript(sentences)}}
+			opts.SummarizeEngine = summarizer
+
+			input := writeTestFile(t, "lecture.mp3", "fake audio")
+			output := filepath.Join(t.TempDir(), "lecture.txt")
+			if _, err := Generate(context.Background(), config, input, output, opts); err != nil {
+				t.Fatal(err)
+			}
+
+			chunks := (&Transcript{Text: numberedTranscript(sentences)}).chunks(config.ChunkTokens, tokenCounter(config.SummarizeModel))
+			if len(chunks) < 2 {
+				t.Fatalf("转录只切分出 %d 块", len(chunks))
+			}
+			if got, want := len(summarizer.prompts), len(chunks)+tt.wantReduce; got != want {
+				t.Errorf("收到 %d 个摘要请求，期望 %d 块各一个加 %d 个整合请求", got, len(chunks), tt.wantReduce)
+			}
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := make([]int, sentences)
+			for i := range want {
+				want[i] = i + 1
+			}
+			if got := sentenceIDs(string(data)); !slices.Equal(got, want) {
+				t.Errorf("笔记中的句子编号为 %v，期望 1-%d 依次出现\n%s", got, sentences, data)
+			}
+		})
+	}
+}
+
+// 流式和非流式的摘要请求都计入 token 用量；流式响应的用量在最后一个数据块中
+func TestCompleteUsage(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		name := "non-stream"
+		if streaming {
+			name = "stream"
+		}
+		t.Run(name, func(t *testing.T) {
+			api := newFakeAPI(t)
+			summarizer, err := newSummarizer(api.config(t), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			var stream io.Writer
+			if streaming {
+				stream = &out
+			}
+
+			ctx, u := withUsage(context.Background())
+			c, err := summarizer.Complete(ctx, "总结这段转录", 100, stream)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Text != "摘要1" {
+				t.Errorf("摘要为 %q，期望 %q", c.Text, "摘要1")
+			}
+			if streaming && strings.TrimSpace(out.String()) != c.Text {
+				t.Errorf("流式输出为 %q，期望与摘要相同", out.String())
+			}
+			if prompt, completion := u.tokens(); prompt != 10 || completion != 5 {
+				t.Errorf("用量为输入 %d、输出 %d tokens，期望 10、5", prompt, completion)
+			}
+		})
+	}
+}
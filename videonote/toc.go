@@ -0,0 +1,131 @@
+package videonote
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// TOC 表示笔记开头的目录样式
+type TOC string
+
+const (
+	// TOCNone 不生成目录
+	TOCNone TOC = ""
+	// TOCMarkdown 生成链接到各部分标题和视频时间点的目录
+	TOCMarkdown TOC = "md"
+	// TOCYouTube 生成可直接粘贴到 YouTube 视频描述中的章节时间戳
+	TOCYouTube TOC = "youtube"
+)
+
+// youtubeMinChapters 为 YouTube 识别章节所需的最少时间戳数量
+const youtubeMinChapters = 3
+
+// tocTitleLength 为没有章节标题时从摘要中截取的目录标题长度 (字符)
+const tocTitleLength = 40
+
+func ParseTOC(s string) (TOC, error) {
+	switch TOC(strings.ToLower(s)) {
+	case TOCNone:
+		return TOCNone, nil
+	case TOCMarkdown, "markdown":
+		return TOCMarkdown, nil
+	case TOCYouTube:
+		return TOCYouTube, nil
+	default:
+		return "", fmt.Errorf("不支持的目录样式: %s (可选: md, youtube)", s)
+	}
+}
+
+// CheckTOC 检查目录样式与输出格式是否兼容
+func CheckTOC(toc TOC, f Format) error {
+	switch {
+	case toc == TOCNone:
+		return nil
+	case toc == TOCMarkdown && f != FormatMarkdown:
+		return fmt.Errorf("-toc md 只适用于 -format md")
+	case toc == TOCYouTube && f != FormatMarkdown && f != FormatText:
+		return fmt.Errorf("-toc youtube 只适用于 -format text 或 md")
+	}
+	return nil
+}
+
+// tocAnchor 返回第 i 个部分 (从0开始) 的锚点，不依赖各渲染器生成标题锚点的规则
+func tocAnchor(i int) string {
+	return fmt.Sprintf("part-%d", i+1)
+}
+
+// tocTitle 返回部分在目录中的标题：优先使用章节标题，其次是摘要中的第一个小标题或第一行
+func tocTitle(section Section, i int) string {
+	if section.Title != "" {
+		return section.Title
+	}
+	for _, line := range strings.Split(section.Summary, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#-*>0123456789. "))
+		line = strings.ReplaceAll(line, "**", "")
+		if line == "" {
+			continue
+		}
+		if utf8.RuneCountInString(line) > tocTitleLength {
+			line = string([]rune(line)[:tocTitleLength]) + "…"
+		}
+		return line
+	}
+	return fmt.Sprintf("第 %d 部分", i+1)
+}
+
+// videoLink 返回跳转到在线视频指定时间的链接，只支持 YouTube，其他来源返回空字符串
+func videoLink(source string, section Section) string {
+	u, err := url.Parse(source)
+	if err != nil || !isURL(source) {
+		return ""
+	}
+	host := strings.TrimPrefix(u.Hostname(), "www.")
+	if host != "youtube.com" && host != "youtu.be" && host != "m.youtube.com" {
+		return ""
+	}
+	q := u.Query()
+	q.Set("t", fmt.Sprintf("%ds", int(section.Start.Seconds())))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// renderTOC 生成笔记开头的目录；没有时间信息的部分无法定位，这类笔记不生成目录
+func renderTOC(toc TOC, info noteInfo, sections []Section, format Format) string {
+	if toc == TOCNone || len(sections) == 0 || !sections[0].Timed {
+		return ""
+	}
+
+	var b strings.Builder
+	switch toc {
+	case TOCMarkdown:
+		b.WriteString("\n## 目录\n\n")
+		for i, section := range sections {
+			marker := section.marker()
+			if link := videoLink(info.Source, section); link != "" {
+				marker = fmt.Sprintf("[%s](%s)", marker, link)
+			}
+			fmt.Fprintf(&b, "- [%s](#%s) %s\n", tocTitle(section, i), tocAnchor(i), marker)
+		}
+	case TOCYouTube:
+		if len(sections) < youtubeMinChapters {
+			warnf("笔记只有%d个部分，YouTube 至少需要%d个章节时间戳才会显示章节", len(sections), youtubeMinChapters)
+		}
+		var lines []string
+		for i, section := range sections {
+			start := section.Start
+			// YouTube 要求第一个章节从 0:00 开始
+			if i == 0 {
+				start = 0
+			}
+			lines = append(lines, formatTimestamp(start)+" "+tocTitle(section, i))
+		}
+		if format == FormatMarkdown {
+			b.WriteString("\n## 章节\n\n```text\n" + strings.Join(lines, "\n") + "\n```\n")
+		} else {
+			b.WriteString(strings.Join(lines, "\n") + "\n\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,154 @@
+package videonote
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// 自动计算的文本块 token 上限不低于该值，过小的块会让摘要失去上下文
+const minChunkTokens = 1000
+
+// modelWindow 为模型的上下文窗口和单次输出上限（token）
+type modelWindow struct {
+	prefix string
+	tokens int
+	output int
+}
+
+// 常见对话模型的上下文窗口，按前缀匹配，越具体的前缀越靠前
+var modelContextWindows = []modelWindow{
+	{"gpt-4.1", 1047576, 32768},
+	{"gpt-4o", 128000, 16384},
+	{"gpt-4-turbo", 128000, 4096},
+	{"gpt-4-32k", 32768, 4096},
+	{"gpt-4", 8192, 4096},
+	{"gpt-3.5-turbo-instruct", 4096, 4096},
+	{"gpt-3.5-turbo", 16385, 4096},
+}
+
+// 未知模型按较保守的上下文窗口和输出上限处理
+const (
+	defaultContextWindow = 8192
+	defaultOutputTokens  = 4096
+)
+
+const (
+	// chunkPromptReserve 为提示词模板、格式和语言说明等预留的 token 数
+	chunkPromptReserve = 1500
+	// chunkCompletionRatio 为按最大摘要比例 (0.5) 和 completionTokens 的 1.5 倍余量估算的输出与输入之比
+	chunkCompletionRatio = 0.5 * 1.5
+)
+
+// chunkTokens 返回每个文本块的 token 上限；未配置 chunk_tokens 时按摘要模型计算，
+// 使文本块、提示词和预计的输出都能放入上下文窗口，且预计的输出不超过模型的单次输出上限
+func (c *Config) chunkTokens() int {
+	if c.ChunkTokens > 0 {
+		return c.ChunkTokens
+	}
+	output := outputLimit(c.SummarizeModel)
+	if c.MaxTokens > 0 {
+		output = c.MaxTokens
+	}
+	byOutput := int(float64(output) / chunkCompletionRatio)
+	return max(min(c.maxChunkTokens(), byOutput), minChunkTokens)
+}
+
+// maxChunkTokens 返回文本块、提示词和预计的输出能放入摘要模型上下文窗口的最大块大小
+func (c *Config) maxChunkTokens() int {
+	window := contextWindow(c.SummarizeModel)
+	return int(float64(window-chunkPromptReserve) / (1 + chunkCompletionRatio))
+}
+
+func contextWindow(model string) int {
+	if w, ok := lookupModelWindow(model); ok {
+		return w.tokens
+	}
+	return defaultContextWindow
+}
+
+// outputLimit 返回模型单次请求最多能输出的 token 数
+func outputLimit(model string) int {
+	if w, ok := lookupModelWindow(model); ok {
+		return w.output
+	}
+	return defaultOutputTokens
+}
+
+func lookupModelWindow(model string) (modelWindow, bool) {
+	model = strings.ToLower(model)
+	for _, w := range modelContextWindows {
+		if strings.HasPrefix(model, w.prefix) {
+			return w, true
+		}
+	}
+	return modelWindow{}, false
+}
+
+var (
+	tokenCountersMu sync.Mutex
+	tokenCounters   = map[string]func(string) int{}
+)
+
+// tokenCounter 返回统计指定模型 token 数的函数；无法加载对应编码（如离线）时退回到粗略估算
+func tokenCounter(model string) func(string) int {
+	tokenCountersMu.Lock()
+	defer tokenCountersMu.Unlock()
+
+	if count, ok := tokenCounters[model]; ok {
+		return count
+	}
+
+	enc, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		enc, err = tiktoken.GetEncoding(tiktoken.MODEL_CL100K_BASE)
+	}
+
+	var count func(string) int
+	if err != nil {
+		warnf("加载分词器失败，将粗略估算token数: %v", err)
+		count = estimateTokens
+	} else {
+		count = func(text string) int {
+			return len(enc.EncodeOrdinary(text))
+		}
+	}
+
+	tokenCounters[model] = count
+	return count
+}
+
+// completionTokens 计算摘要请求的 MaxTokens：按比例估算摘要长度，并保证不超出模型上下文窗口
+func completionTokens(model string, promptTokens, textTokens int, ratio float64) int {
+	const minCompletionTokens = 256
+
+	maxTokens := max(int(float64(textTokens)*ratio*1.5), minCompletionTokens)
+	if available := contextWindow(model) - promptTokens; maxTokens > available {
+		maxTokens = available
+	}
+	return maxTokens
+}
+
+// RegisterChunkSizeFlag 注册 -chunk-size，设置后覆盖配置文件中的 chunk_tokens
+func RegisterChunkSizeFlag(fs *flag.FlagSet, config *Config) {
+	fs.Func("chunk-size", "每个文本块的token上限 (默认按摘要模型的上下文窗口和输出上限自动计算)", func(s string) error {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 100 {
+			return fmt.Errorf("无效的 -chunk-size: %s (至少为 100)", s)
+		}
+		config.ChunkTokens = v
+		return nil
+	})
+}
+
+// ValidateChunkOverlap 检查 -chunk-overlap，重叠部分必须小于每块的token上限
+func (c *Config) ValidateChunkOverlap(overlap int) error {
+	if overlap < 0 || overlap >= c.chunkTokens() {
+		return fmt.Errorf("-chunk-overlap 必须在 0 到 %d 之间 (小于每块的token上限)", c.chunkTokens()-1)
+	}
+	return nil
+}
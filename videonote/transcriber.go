@@ -0,0 +1,497 @@
+package videonote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// 可选的转录后端
+const (
+	transcriberOpenAI = "openai"
+	transcriberLocal  = "local"
+)
+
+// 默认的 whisper.cpp 可执行文件名，旧版本中为 main
+const defaultWhisperBinary = "whisper-cli"
+
+// Transcriber 将音频转录为文本。实现只负责调用转录服务，缓存、说话人分离和
+// 写入输出文件由 Transcribe 统一处理，新增后端时只需实现该接口并在 newTranscriber 中注册
+type Transcriber interface {
+	Transcribe(ctx context.Context, audioPath string) (*Transcript, error)
+}
+
+// newTranscriber 按 opts.Backend 创建转录后端
+func newTranscriber(config *Config, opts TranscribeOptions) (Transcriber, error) {
+	switch opts.Backend {
+	case "", transcriberOpenAI:
+		return &OpenAITranscriber{config: config, opts: opts, caps: transcribeCapabilities(config.TranscribeModel)}, nil
+	case transcriberLocal:
+		if config.WhisperModel == "" {
+			return nil, fmt.Errorf("使用本地转录需要在配置文件中设置 whisper_model (whisper.cpp 的 ggml 模型文件)")
+		}
+		return &LocalTranscriber{config: config, opts: opts}, nil
+	default:
+		return nil, fmt.Errorf("不支持的转录后端: %s (可选: openai, local)", opts.Backend)
+	}
+}
+
+// OpenAITranscriber 调用 OpenAI 的 Whisper 接口转录
+type OpenAITranscriber struct {
+	config *Config
+	opts   TranscribeOptions
+	caps   transcribeModel
+}
+
+// Transcribe 转录音频，过大的音频会先切分再并发转录各段
+func (t *OpenAITranscriber) Transcribe(ctx context.Context, audioPath string) (*Transcript, error) {
+	config, opts := t.config, t.opts
+	client := newOpenAIClient(config)
+
+	// 转录接口不接受 .aac、.opus、.wma 等格式，先转码为 mp3
+	if !transcribableExtensions[fileExt(audioPath)] {
+		if err := CheckFFmpeg(config); err != nil {
+			return nil, err
+		}
+		dir, err := os.MkdirTemp("", "video-note-audio-")
+		if err != nil {
+			return nil, fmt.Errorf("创建临时目录失败: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		converted := filepath.Join(dir, "audio"+ExtractOptions{}.audioExt())
+		infof("转录接口不支持 %s 格式，先转码为 %s", fileExt(audioPath), strings.TrimPrefix(filepath.Ext(converted), "."))
+		if _, err := extractAudio(ctx, config, audioPath, converted, ExtractOptions{AudioTrack: -1}); err != nil {
+			return nil, fmt.Errorf("转码音频失败: %w", err)
+		}
+		audioPath = converted
+	}
+
+	info, err := os.Stat(audioPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开音频文件失败: %w", err)
+	}
+
+	// 超过接口大小限制的音频需要先切分再分段转录；-pipeline 时总是切分，以便尽早开始摘要
+	segments := []string{audioPath}
+	segmentTime := opts.SegmentTime
+	if info.Size() > maxAudioFileSize || opts.onPart != nil {
+		segmentDir, err := os.MkdirTemp("", "video-note-segments-")
+		if err != nil {
+			return nil, fmt.Errorf("创建临时目录失败: %w", err)
+		}
+		defer os.RemoveAll(segmentDir)
+
+		// 只有需要切分时才依赖 ffmpeg，普通音频转录不要求安装
+		if err := CheckFFmpeg(config); err != nil {
+			return nil, err
+		}
+
+		segments, segmentTime, err = splitAudio(ctx, config, audioPath, segmentDir, opts.SegmentTime)
+		if err != nil {
+			return nil, fmt.Errorf("切分音频失败: %w", err)
+		}
+		if opts.onPart == nil {
+			infof("音频文件超过25MB，已切分为%d段", len(segments))
+		}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+
+	// 各段并发转录，前面的段都完成后按顺序拼接，并通过 onPart 交给后续的摘要
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([]*segmentResult, len(segments))
+	transcript := &Transcript{}
+	var mu sync.Mutex
+	next := 0
+	var wg sync.WaitGroup
+	errChan := make(chan error, len(segments))
+	sem := make(chan struct{}, concurrency)
+	bar := newProgress("正在转录音频", len(segments), opts.Quiet)
+	for i, segment := range segments {
+		wg.Add(1)
+		go func(idx int, segment string) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result, err := t.transcribeSegment(ctx, client, segment)
+			if err != nil {
+				// 一段失败后取消其余请求，不必等全部完成
+				cancel()
+				if len(segments) > 1 {
+					errChan <- fmt.Errorf("转录第%d/%d段音频 (%s 起) 失败: %w",
+						idx+1, len(segments), formatTimestamp(time.Duration(idx)*segmentTime), err)
+				} else {
+					errChan <- err
+				}
+				return
+			}
+			debugf("转录第%d/%d段音频完成，耗时 %v", idx+1, len(segments), result.elapsed.Round(time.Millisecond))
+			bar.Increment()
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[idx] = &result
+			for next < len(results) && results[next] != nil {
+				part := t.appendResult(transcript, next, len(segments), segmentTime, *results[next])
+				if opts.onPart != nil {
+					opts.onPart(part)
+				}
+				next++
+			}
+		}(i, segment)
+	}
+	wg.Wait()
+	close(errChan)
+	// 取消引起的错误不是失败的原因，优先返回其他错误
+	var firstErr error
+	for err := range errChan {
+		if firstErr == nil || (errors.Is(firstErr, context.Canceled) && !errors.Is(err, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return transcript, nil
+}
+
+// appendResult 将第 i 段 (共 n 段，每段 segmentTime) 的结果按时间偏移拼接到 transcript，返回新增的部分
+func (t *OpenAITranscriber) appendResult(transcript *Transcript, i, n int, segmentTime time.Duration, result segmentResult) *Transcript {
+	opts := t.opts
+	// 只有 verbose_json 会返回识别出的语言
+	if transcript.Language == "" {
+		transcript.Language = result.language
+	}
+	part := &Transcript{Language: transcript.Language}
+
+	offset := time.Duration(i) * segmentTime
+	for _, c := range result.confidence {
+		if i < n-1 && c.Start >= segmentTime {
+			break
+		}
+		c.Start, c.End = offset+c.Start, offset+c.End
+		transcript.Confidence = append(transcript.Confidence, c)
+		part.Confidence = append(part.Confidence, c)
+	}
+
+	if !opts.Timestamps {
+		prev := transcript.Text
+		transcript.Text = mergeOverlap(prev, result.text)
+		part.Text = strings.TrimSpace(strings.TrimPrefix(transcript.Text, prev))
+		return part
+	}
+
+	for _, seg := range result.segments {
+		start := offset + seg.Start
+		// 分段末尾的重叠部分由下一段负责，避免重复
+		if i < n-1 && start >= offset+segmentTime {
+			break
+		}
+		seg.Start, seg.End = start, offset+seg.End
+		transcript.Segments = append(transcript.Segments, seg)
+		part.Segments = append(part.Segments, seg)
+	}
+	part.Text = part.joinSegments()
+	return part
+}
+
+// segmentResult 为一段音频的转录结果，片段的时间相对于该段开头
+type segmentResult struct {
+	text       string
+	segments   []Segment
+	confidence []SegmentConfidence
+	language   string
+	elapsed    time.Duration
+}
+
+// transcribeSegment 调用接口转录一段不超过大小限制的音频
+func (t *OpenAITranscriber) transcribeSegment(ctx context.Context, client *openai.Client, segment string) (segmentResult, error) {
+	config, opts := t.config, t.opts
+	format := opts.responseFormat()
+	req := openai.AudioRequest{
+		Model:       config.TranscribeModel,
+		FilePath:    segment,
+		Language:    opts.Language,
+		Prompt:      opts.Prompt,
+		Format:      format,
+		Temperature: opts.Temperature,
+	}
+	if t.caps.chunking {
+		// 说话人分离模型处理较长的音频时要求指定切分方式
+		req.ChunkingStrategy = "auto"
+	}
+
+	var resp openai.AudioResponse
+	start := time.Now()
+	err := withRetry(ctx, config.MaxAttempts, countCalls(func() (err error) {
+		if opts.Translate {
+			resp, err = client.CreateTranslation(ctx, req)
+		} else {
+			resp, err = client.CreateTranscription(ctx, req)
+		}
+		return err
+	}))
+	if err != nil {
+		return segmentResult{}, fmt.Errorf("调用OpenAI API失败: %w", err)
+	}
+	result := segmentResult{
+		text:     resp.Text,
+		language: whisperLanguageCodes[strings.ToLower(resp.Language)],
+		elapsed:  time.Since(start),
+	}
+
+	// 字幕格式由 Whisper 直接给出每条字幕的时间，解析后与 verbose_json 的片段一样处理
+	switch format {
+	case openai.AudioResponseFormatVerboseJSON:
+		for _, seg := range resp.Segments {
+			start, end := secondsToDuration(seg.Start), secondsToDuration(seg.End)
+			result.segments = append(result.segments, Segment{
+				Start: start,
+				End:   end,
+				Text:  strings.TrimSpace(seg.Text),
+			})
+			result.confidence = append(result.confidence, SegmentConfidence{
+				Start:        start,
+				End:          end,
+				AvgLogprob:   seg.AvgLogprob,
+				NoSpeechProb: seg.NoSpeechProb,
+			})
+		}
+	case openai.AudioResponseFormatSRT, openai.AudioResponseFormatVTT:
+		if result.segments, err = parseSubtitles(resp.Text); err != nil {
+			return segmentResult{}, fmt.Errorf("解析Whisper返回的字幕失败: %w", err)
+		}
+		result.text = (&Transcript{Segments: result.segments}).joinSegments()
+	}
+	return result, nil
+}
+
+// Whisper 支持的响应格式
+var whisperFormats = map[string]bool{
+	string(openai.AudioResponseFormatText):        true,
+	string(openai.AudioResponseFormatJSON):        true,
+	string(openai.AudioResponseFormatVerboseJSON): true,
+	string(openai.AudioResponseFormatSRT):         true,
+	string(openai.AudioResponseFormatVTT):         true,
+}
+
+// transcribeModel 为转录模型支持的功能
+type transcribeModel struct {
+	// timestamps 为 true 时支持 verbose_json、srt 和 vtt，可以得到片段时间戳
+	timestamps bool
+	// translate 为 true 时可以调用翻译接口
+	translate bool
+	// prompt 为 true 时接受提示文本
+	prompt bool
+	// chunking 为 true 时需要在请求中指定 chunking_strategy
+	chunking bool
+}
+
+// whisperModel 为 whisper-1 及兼容接口上 Whisper 系列模型的功能，也用于无法识别的模型，
+// 与只支持 whisper-1 时的行为保持一致
+var whisperModel = transcribeModel{timestamps: true, translate: true, prompt: true}
+
+// gpt-4o-transcribe 系列只返回 text 或 json，不提供时间戳和翻译接口
+var transcribeModels = map[string]transcribeModel{
+	openai.Whisper1:               whisperModel,
+	openai.GPT4oTranscribe:        {prompt: true},
+	openai.GPT4oMiniTranscribe:    {prompt: true},
+	openai.GPT4oTranscribeDiarize: {chunking: true},
+}
+
+// transcribeCapabilities 返回模型支持的功能；未列出的模型名中含 transcribe 时按 gpt-4o-transcribe 系列处理
+func transcribeCapabilities(model string) transcribeModel {
+	name := strings.ToLower(model)
+	if caps, ok := transcribeModels[name]; ok {
+		return caps
+	}
+	if strings.Contains(name, "transcribe") {
+		return transcribeModels[openai.GPT4oTranscribe]
+	}
+	return whisperModel
+}
+
+// check 检查转录选项是否为模型所支持；时间戳只用于标注笔记时，模型不支持则给出警告并关闭
+func (caps transcribeModel) check(model string, opts *TranscribeOptions) error {
+	if opts.Translate && !caps.translate {
+		return fmt.Errorf("转录模型 %s 不支持翻译，-translate 需要使用 %s", model, openai.Whisper1)
+	}
+	if !caps.timestamps {
+		switch {
+		case opts.Format.isSubtitle():
+			return fmt.Errorf("转录模型 %s 不返回时间戳，不能输出 %s 字幕，请使用 %s", model, opts.Format, openai.Whisper1)
+		case opts.Diarize:
+			return fmt.Errorf("转录模型 %s 不返回片段时间戳，无法与 diarize_command 的结果对齐，请使用 %s", model, openai.Whisper1)
+		case opts.ResponseFormat != "" && opts.ResponseFormat != string(openai.AudioResponseFormatText) &&
+			opts.ResponseFormat != string(openai.AudioResponseFormatJSON):
+			return fmt.Errorf("转录模型 %s 只支持 text 和 json 响应格式，不支持 %s", model, opts.ResponseFormat)
+		case opts.Timestamps:
+			warnf("转录模型 %s 不返回时间戳，笔记中不会标注视频时间，也无法按章节划分", model)
+			opts.Timestamps = false
+		}
+		// 默认不检查置信度，小于 0 说明用户明确指定了 -confidence-threshold
+		if opts.ConfidenceThreshold < 0 {
+			warnf("转录模型 %s 不返回置信度，已忽略 -confidence-threshold", model)
+			opts.ConfidenceThreshold = 0
+		}
+	}
+	if opts.Timestamps && opts.ResponseFormat != "" && !isTimedFormat(openai.AudioResponseFormat(opts.ResponseFormat)) {
+		warnf("需要片段时间戳，Whisper响应格式 %s 不含时间信息，改用 verbose_json", opts.ResponseFormat)
+		opts.ResponseFormat = string(openai.AudioResponseFormatVerboseJSON)
+	}
+	if opts.Prompt != "" && !caps.prompt {
+		warnf("转录模型 %s 不支持提示文本，已忽略 -prompt", model)
+		opts.Prompt = ""
+	}
+	return nil
+}
+
+// ValidateWhisperOptions 检查 -whisper-format 和 -whisper-temperature
+func ValidateWhisperOptions(format string, temperature float64) error {
+	if format != "" && !whisperFormats[format] {
+		return fmt.Errorf("不支持的Whisper响应格式: %s (可选: text, json, verbose_json, srt, vtt)", format)
+	}
+	if temperature < 0 || temperature > 1 {
+		return fmt.Errorf("Whisper的采样温度必须在 0 到 1 之间，当前为 %g", temperature)
+	}
+	return nil
+}
+
+// responseFormat 返回请求 Whisper 的响应格式：未指定时需要时间戳或置信度用 verbose_json，否则用 text。
+// 指定的格式不含时间信息但需要时间戳时，已由 check 改为 verbose_json
+func (o TranscribeOptions) responseFormat() openai.AudioResponseFormat {
+	switch {
+	case o.ResponseFormat != "":
+		return openai.AudioResponseFormat(o.ResponseFormat)
+	case o.Timestamps || o.ConfidenceThreshold < 0:
+		return openai.AudioResponseFormatVerboseJSON
+	default:
+		return openai.AudioResponseFormatText
+	}
+}
+
+// isTimedFormat 判断响应格式是否包含片段时间
+func isTimedFormat(format openai.AudioResponseFormat) bool {
+	return format == openai.AudioResponseFormatVerboseJSON ||
+		format == openai.AudioResponseFormatSRT || format == openai.AudioResponseFormatVTT
+}
+
+// LocalTranscriber 调用本地的 whisper.cpp 转录，音频不会离开本机
+type LocalTranscriber struct {
+	config *Config
+	opts   TranscribeOptions
+}
+
+func (t *LocalTranscriber) Transcribe(ctx context.Context, audioPath string) (*Transcript, error) {
+	opts := t.opts
+	dir, err := os.MkdirTemp("", "video-note-whisper-")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// whisper.cpp 只接受 16kHz 单声道 WAV，先用 ffmpeg 转换
+	if err := CheckFFmpeg(t.config); err != nil {
+		return nil, err
+	}
+	wavPath := filepath.Join(dir, "audio.wav")
+	cmd := exec.CommandContext(ctx, t.config.ffmpegBinary(), "-y", "-i", audioPath,
+		"-vn", "-ar", "16000", "-ac", "1", "-acodec", "pcm_s16le", wavPath)
+	debugCommand(cmd)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("转换为WAV失败: %w", ffmpegError("ffmpeg", audioPath, err, output))
+	}
+
+	language := opts.Language
+	if language == "" {
+		language = "auto"
+	}
+	outputBase := filepath.Join(dir, "transcript")
+	args := []string{"-m", t.config.WhisperModel, "-f", wavPath, "-l", language,
+		"-oj", "-of", outputBase, "-np"}
+	if opts.Prompt != "" {
+		args = append(args, "--prompt", opts.Prompt)
+	}
+	if opts.Translate {
+		args = append(args, "-tr")
+	}
+
+	infof("正在使用本地 whisper.cpp 转录音频...")
+	start := time.Now()
+	cmd = exec.CommandContext(ctx, t.config.whisperBinary(), args...)
+	debugCommand(cmd)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("whisper.cpp执行失败: %w\n输出: %s", err, string(output))
+	}
+	debugf("本地转录完成，耗时 %v", time.Since(start).Round(time.Millisecond))
+
+	data, err := os.ReadFile(outputBase + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("读取whisper.cpp输出失败: %w", err)
+	}
+	return parseWhisperCppJSON(data, opts.Timestamps)
+}
+
+// parseWhisperCppJSON 解析 whisper.cpp -oj 输出的 JSON，offsets 为毫秒；
+// timestamps 为 false 时只保留全文
+func parseWhisperCppJSON(data []byte, timestamps bool) (*Transcript, error) {
+	var result struct {
+		Result struct {
+			Language string `json:"language"`
+		} `json:"result"`
+		Transcription []struct {
+			Offsets struct {
+				From int64 `json:"from"`
+				To   int64 `json:"to"`
+			} `json:"offsets"`
+			Text string `json:"text"`
+		} `json:"transcription"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析whisper.cpp输出失败: %w", err)
+	}
+
+	transcript := &Transcript{Language: strings.ToLower(result.Result.Language)}
+	// whisper.cpp 在英文等语言的片段开头保留空格，直接拼接即可得到正确的分词
+	var b strings.Builder
+	for _, item := range result.Transcription {
+		text := strings.TrimSpace(item.Text)
+		if text == "" {
+			continue
+		}
+		b.WriteString(item.Text)
+		if timestamps {
+			transcript.Segments = append(transcript.Segments, Segment{
+				Start: time.Duration(item.Offsets.From) * time.Millisecond,
+				End:   time.Duration(item.Offsets.To) * time.Millisecond,
+				Text:  text,
+			})
+		}
+	}
+	transcript.Text = strings.TrimSpace(b.String())
+	return transcript, nil
+}
+
+// whisperBinary 返回 whisper.cpp 可执行文件路径
+func (c *Config) whisperBinary() string {
+	if c.WhisperBinary != "" {
+		return c.WhisperBinary
+	}
+	return defaultWhisperBinary
+}
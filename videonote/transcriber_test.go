@@ -0,0 +1,179 @@
+package videonote
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestTranscribeResponseFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      string
+		timestamps bool
+		threshold  float64
+		want       string
+	}{
+		{"whisper default", openai.Whisper1, false, DefaultConfidenceThreshold, "text"},
+		{"whisper timestamps", openai.Whisper1, true, DefaultConfidenceThreshold, "verbose_json"},
+		{"whisper confidence", openai.Whisper1, false, SuggestedConfidenceThreshold, "verbose_json"},
+		{"gpt-4o default", openai.GPT4oTranscribe, false, DefaultConfidenceThreshold, "text"},
+		{"gpt-4o confidence ignored", openai.GPT4oTranscribe, false, SuggestedConfidenceThreshold, "text"},
+		{"gpt-4o-mini confidence ignored", openai.GPT4oMiniTranscribe, false, SuggestedConfidenceThreshold, "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI(t)
+			config := api.config(t)
+			config.TranscribeModel = tt.model
+
+			input := writeTestFile(t, "talk.mp3", "fake audio")
+			_, err := Transcribe(context.Background(), config, input, filepath.Join(t.TempDir(), "talk.txt"), TranscribeOptions{
+				Format:              FormatText,
+				SegmentTime:         10 * time.Minute,
+				Timestamps:          tt.timestamps,
+				ConfidenceThreshold: tt.threshold,
+				Quiet:               true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := api.Formats(); !reflect.DeepEqual(got, []string{tt.want}) {
+				t.Errorf("response_format 为 %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranscribeCacheConfidence(t *testing.T) {
+	api := newFakeAPI(t)
+	config := api.config(t)
+	input := writeTestFile(t, "talk.mp3", "fake audio")
+	cacheDir := t.TempDir()
+
+	transcribe := func(threshold float64) {
+		t.Helper()
+		_, err := Transcribe(context.Background(), config, input, filepath.Join(t.TempDir(), "talk.txt"), TranscribeOptions{
+			Format:              FormatText,
+			SegmentTime:         10 * time.Minute,
+			ConfidenceThreshold: threshold,
+			CacheDir:            cacheDir,
+			Quiet:               true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transcribe(DefaultConfidenceThreshold)
+	transcribe(DefaultConfidenceThreshold)
+	if n := api.Calls(transcriptionsPath); n != 1 {
+		t.Fatalf("相同的选项应使用缓存，收到 %d 个转录请求", n)
+	}
+	// 只请求 text 时缓存的转录没有置信度，检查置信度时需要重新转录
+	transcribe(SuggestedConfidenceThreshold)
+	if got := api.Formats(); !reflect.DeepEqual(got, []string{"text", "verbose_json"}) {
+		t.Errorf("response_format 为 %q", got)
+	}
+	transcribe(SuggestedConfidenceThreshold)
+	if n := api.Calls(transcriptionsPath); n != 2 {
+		t.Errorf("收到 %d 个转录请求，期望 2 个", n)
+	}
+}
+
+// fakeTranscriber 返回固定的转录结果，并记录收到的音频路径
+type fakeTranscriber struct {
+	transcript Transcript
+
+	mu    sync.Mutex
+	paths []string
+}
+
+func (f *fakeTranscriber) Transcribe(ctx context.Context, audioPath string) (*Transcript, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paths = append(f.paths, audioPath)
+	transcript := f.transcript
+	transcript.Segments = append([]Segment(nil), f.transcript.Segments...)
+	return &transcript, nil
+}
+
+// 注入的转录后端代替 OpenAI 接口，转录结果照常生成摘要和字幕
+func TestGenerateWithTranscribeEngine(t *testing.T) {
+	api := newFakeAPI(t)
+	api.Complete = func(string) string { return "测试摘要" }
+	engine := &fakeTranscriber{transcript: Transcript{Segments: []Segment{
+		{Start: 0, End: 4 * time.Second, Text: "来自模拟转录后端的第一句。"},
+		{Start: 4 * time.Second, End: 9 * time.Second, Text: "第二句。"},
+	}}}
+
+	input := writeTestFile(t, "lecture.mp3", "fake audio")
+	output := filepath.Join(t.TempDir(), "lecture.txt")
+	opts := testGenerateOptions()
+	opts.TranscribeEngine = engine
+	opts.CacheDir = t.TempDir()
+	opts.Outputs = []Artifact{ArtifactSummary, ArtifactSRT}
+	if _, err := Generate(context.Background(), api.config(t), input, output, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := api.Calls(transcriptionsPath); n != 0 {
+		t.Errorf("使用注入的转录后端时仍调用了 %d 次转录接口", n)
+	}
+	if !reflect.DeepEqual(engine.paths, []string{input}) {
+		t.Errorf("转录后端收到的音频为 %q，期望 %q", engine.paths, input)
+	}
+	if prompts := api.Prompts(); len(prompts) != 1 || !strings.Contains(prompts[0], "来自模拟转录后端的第一句。 第二句。") {
+		t.Errorf("摘要请求中没有模拟的转录: %q", prompts)
+	}
+	srt, err := os.ReadFile(strings.TrimSuffix(output, ".txt") + ".srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(srt), "00:00:04,000 --> 00:00:09,000\n第二句。") {
+		t.Errorf("字幕中没有模拟的片段:\n%s", srt)
+	}
+}
+
+// 转录接口不接受的音频格式先用 ffmpeg 转码为 mp3 再上传
+func TestTranscribeConvertsUnsupportedAudio(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("模拟的 ffmpeg 为 shell 脚本")
+	}
+	api := newFakeAPI(t)
+	config := api.config(t)
+	// 模拟的 ffmpeg 把输入复制到最后一个参数 (输出文件)，ffprobe 报告 60 秒
+	dir := filepath.Dir(config.FFmpegPath)
+	scripts := map[string]string{
+		"ffmpeg":  "#!/bin/sh\nwhile [ $# -gt 1 ]; do [ \"$1\" = -i ] && in=$2; shift; done\ncp \"$in\" \"$1\"\n",
+		"ffprobe": "#!/bin/sh\necho 60\n",
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, ext := range []string{".aac", ".opus", ".wma"} {
+		input := writeTestFile(t, "talk"+ext, "fake audio")
+		if _, err := Transcribe(context.Background(), config, input, filepath.Join(t.TempDir(), "talk.txt"), TranscribeOptions{
+			Format:      FormatText,
+			SegmentTime: 10 * time.Minute,
+			Quiet:       true,
+		}); err != nil {
+			t.Fatalf("%s: %v", ext, err)
+		}
+	}
+	if files := api.Files(); !slices.Equal(files, []string{"audio.mp3", "audio.mp3", "audio.mp3"}) {
+		t.Errorf("上传的文件为 %v，期望都是转码后的 mp3", files)
+	}
+}
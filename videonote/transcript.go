@@ -0,0 +1,295 @@
+package videonote
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Segment 为带时间戳的一段转录文本
+type Segment struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+	// Speaker 为说话人分离后标注的说话人，如 Speaker 1
+	Speaker string
+}
+
+// Transcript 为音频转录结果，仅在请求时间戳时才包含 Segments
+type Transcript struct {
+	Text     string
+	Segments []Segment
+	// Chapters 为视频的章节，存在时按章节切分文本块
+	Chapters []Chapter `json:"-"`
+	// Language 为转录接口识别出的语言代码，可能为空
+	Language string
+	// Confidence 为 Whisper 返回的各片段置信度，时间与 Segments 一致；只有 verbose_json 响应才有
+	Confidence []SegmentConfidence `json:",omitempty"`
+}
+
+// language 返回转录内容的语言，优先使用转录接口识别的结果
+func (t *Transcript) language() string {
+	if t.Language != "" {
+		return t.Language
+	}
+	return detectLanguage(t.Text)
+}
+
+// textChunk 为送去生成摘要的一段文本，Timed 表示 Start 是否有效
+type textChunk struct {
+	Text  string
+	Start time.Duration
+	Timed bool
+	// Chapter 为所属章节的序号 (从1开始)，0 表示没有章节
+	Chapter int
+	Title   string
+	// Context 为前一块结尾的文本，只用于帮助理解衔接处的内容，不单独摘要
+	Context string
+}
+
+// chunkRange 返回第 idx 块对应的时间范围，如 " (00:10:00-00:20:00)"；没有时间信息时为空
+func chunkRange(chunks []textChunk, idx int, transcript *Transcript) string {
+	chunk := chunks[idx]
+	if !chunk.Timed {
+		return ""
+	}
+	var end time.Duration
+	if idx+1 < len(chunks) && chunks[idx+1].Timed {
+		end = chunks[idx+1].Start
+	} else if n := len(transcript.Segments); n > 0 {
+		end = transcript.Segments[n-1].End
+	}
+	if end <= chunk.Start {
+		return " (" + formatTimestamp(chunk.Start) + " 起)"
+	}
+	return " (" + formatTimestamp(chunk.Start) + "-" + formatTimestamp(end) + ")"
+}
+
+// addOverlap 为每块附上前一块结尾约 overlap 个 token 的文本作为上下文，跨章节时不附加
+func addOverlap(chunks []textChunk, overlap int, count func(string) int) {
+	if overlap <= 0 {
+		return
+	}
+	for i := 1; i < len(chunks); i++ {
+		if chunks[i].Chapter != chunks[i-1].Chapter {
+			continue
+		}
+		chunks[i].Context = tailText(chunks[i-1].Text, overlap, count)
+	}
+}
+
+// tailText 返回 text 结尾处 token 数不超过 limit 的部分
+func tailText(text string, limit int, count func(string) int) string {
+	words := splitLongWords(strings.Fields(text), limit, count)
+	start, tokens := len(words), 0
+	for start > 0 {
+		n := count(words[start-1])
+		if tokens+n > limit {
+			break
+		}
+		tokens += n
+		start--
+	}
+	return strings.Join(words[start:], " ")
+}
+
+// chunks 将转录结果切分为 token 数不超过 limit 的文本块；有时间戳时按片段边界切分，以便记录每块的起始时间，
+// 有章节时先按章节切分，过长的章节再拆成多块
+func (t *Transcript) chunks(limit int, count func(string) int) []textChunk {
+	if len(t.Segments) == 0 {
+		var chunks []textChunk
+		for _, text := range splitTextIntoChunks(t.Text, limit, count) {
+			chunks = append(chunks, textChunk{Text: text})
+		}
+		return chunks
+	}
+	if len(t.Chapters) > 0 {
+		return t.chapterChunks(limit, count)
+	}
+	return segmentChunks(t.Segments, limit, count)
+}
+
+func (t *Transcript) chapterChunks(limit int, count func(string) int) []textChunk {
+	var chunks []textChunk
+	i := 0
+	for n, chapter := range t.Chapters {
+		// 章节开始前的片段归入第一章，最后一章收下剩余的全部片段
+		start := i
+		for i < len(t.Segments) && (n == len(t.Chapters)-1 || t.Segments[i].Start < t.Chapters[n+1].Start) {
+			i++
+		}
+		for j, chunk := range segmentChunks(t.Segments[start:i], limit, count) {
+			chunk.Chapter = n + 1
+			chunk.Title = chapter.Title
+			if j == 0 {
+				chunk.Start = chapter.Start
+			}
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+// segmentChunks 按片段边界切分，每块开头以及说话人变化处标注说话人；单个片段超过 limit 时按句子拆开，
+// 拆开的部分使用原片段的起始时间
+func segmentChunks(segments []Segment, limit int, count func(string) int) []textChunk {
+	var pieces []Segment
+	for _, seg := range segments {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+		seg.Text = text
+		if count(speakerLabel(seg.Speaker)+text) <= limit {
+			pieces = append(pieces, seg)
+			continue
+		}
+		for _, part := range splitTextIntoChunks(text, limit-count(speakerLabel(seg.Speaker)), count) {
+			seg.Text = part
+			pieces = append(pieces, seg)
+		}
+	}
+
+	join := func(i, j int) string {
+		var b strings.Builder
+		for k, seg := range pieces[i:j] {
+			if k > 0 {
+				b.WriteString(" ")
+			}
+			if k == 0 || seg.Speaker != pieces[i+k-1].Speaker {
+				b.WriteString(speakerLabel(seg.Speaker))
+			}
+			b.WriteString(seg.Text)
+		}
+		return b.String()
+	}
+	var chunks []textChunk
+	start := 0
+	for _, end := range packChunks(len(pieces), limit, join, count) {
+		chunks = append(chunks, textChunk{Text: join(start, end), Start: pieces[start].Start, Timed: true})
+		start = end
+	}
+	return chunks
+}
+
+// speakerLabel 返回文本块中标注说话人的前缀，没有说话人时为空
+func speakerLabel(speaker string) string {
+	if speaker == "" {
+		return ""
+	}
+	return "[" + speaker + "] "
+}
+
+// joinSegments 拼接各片段的文本，有说话人标注时每位说话人的发言另起一行
+func (t *Transcript) joinSegments() string {
+	var b strings.Builder
+	speaker := ""
+	for i, seg := range t.Segments {
+		switch {
+		case seg.Speaker != "" && seg.Speaker != speaker:
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString("[" + seg.Speaker + "] ")
+		case i > 0:
+			b.WriteString(" ")
+		}
+		speaker = seg.Speaker
+		b.WriteString(seg.Text)
+	}
+	return b.String()
+}
+
+func (t *Transcript) hasSpeakers() bool {
+	for _, seg := range t.Segments {
+		if seg.Speaker != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// formatTimestamp 将时间格式化为 mm:ss，超过一小时时为 h:mm:ss
+func formatTimestamp(d time.Duration) string {
+	total := int(d / time.Second)
+	h, m, s := total/3600, total/60%60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// 句末标点，之后紧跟的引号和括号仍属于同一句
+const (
+	sentenceEnders = ".?!。！？"
+	closingMarks   = "\"')]”’」』）】"
+)
+
+// abbreviations 为以 . 结尾但通常不是句末的英文缩写 (小写)
+var abbreviations = map[string]bool{
+	"mr.": true, "mrs.": true, "ms.": true, "dr.": true, "prof.": true, "st.": true,
+	"vs.": true, "etc.": true, "no.": true, "fig.": true, "approx.": true,
+}
+
+// dottedInitials 匹配 e.g.、U.S.、a.m. 这类每个字母后都带 . 的缩写
+var dottedInitials = regexp.MustCompile(`^(\p{L}\.){2,}$`)
+
+// isAbbreviation 判断以 . 结尾的单词是否为缩写
+func isAbbreviation(word string) bool {
+	word = strings.TrimLeft(word, "\"'([“‘「『（【")
+	return abbreviations[strings.ToLower(word)] || dottedInitials.MatchString(word)
+}
+
+// splitSentences 按句末标点将文本切分为句子；英文的 . ? ! 之后需要有空白才算句末，
+// 以免拆开 3.14 这样的写法，e.g.、Mr. 等缩写之后也不断句；中文句号、问号、感叹号之后直接断句
+func splitSentences(text string) []string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	var sentences []string
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		if !strings.ContainsRune(sentenceEnders, runes[i]) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && (strings.ContainsRune(sentenceEnders, runes[end]) || strings.ContainsRune(closingMarks, runes[end])) {
+			end++
+		}
+		if runes[i] < utf8.RuneSelf && end < len(runes) && runes[end] != ' ' {
+			continue
+		}
+		if runes[i] == '.' && end == i+1 && end < len(runes) {
+			wordStart := i
+			for wordStart > start && runes[wordStart-1] != ' ' {
+				wordStart--
+			}
+			if isAbbreviation(string(runes[wordStart:end])) {
+				continue
+			}
+		}
+		if sentence := strings.TrimSpace(string(runes[start:end])); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start, i = end, end-1
+	}
+	if rest := strings.TrimSpace(string(runes[start:])); rest != "" {
+		sentences = append(sentences, rest)
+	}
+	return sentences
+}
+
+// joinText 拼接两段文本，前一段以中日韩文字或全角标点结尾时不加空格
+func joinText(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if r, _ := utf8.DecodeLastRuneInString(a); isCJK(r) {
+		return a + b
+	}
+	return a + " " + b
+}
@@ -0,0 +1,85 @@
+package videonote
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// withoutSpace 去掉空白，用于比较切分前后的内容
+func withoutSpace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+func TestChunksWithinTokenLimit(t *testing.T) {
+	const limit = 120
+	texts := map[string]string{
+		"mixed sentences": strings.Repeat("今天我们讨论 Kubernetes 的调度策略。The scheduler assigns pods to nodes based on resources! 这部分需要注意资源的预留？", 12),
+		"no punctuation":  strings.Repeat("without any sentence boundary the text has to be split between words ", 20),
+		"long word":       strings.Repeat("超长的没有空格也没有标点的中文文本", 30),
+	}
+	// estimateTokens 为加载分词器失败时 tokenCounter 使用的粗略估算
+	counters := map[string]func(string) int{
+		"tokenizer": tokenCounter(openai.GPT4oMini),
+		"fallback":  estimateTokens,
+	}
+	for counterName, count := range counters {
+		for textName, text := range texts {
+			t.Run(counterName+"/"+textName, func(t *testing.T) {
+				chunks := splitTextIntoChunks(text, limit, count)
+				if len(chunks) < 2 {
+					t.Fatalf("只切分出 %d 块", len(chunks))
+				}
+				for i, chunk := range chunks {
+					if n := count(chunk); n > limit {
+						t.Errorf("第 %d 块有 %d 个 token，超过上限 %d: %q", i+1, n, limit, chunk)
+					}
+				}
+				if got := withoutSpace(strings.Join(chunks, "")); got != withoutSpace(text) {
+					t.Errorf("切分后的内容与原文不一致")
+				}
+
+				// 带时间戳的片段按同样的上限切分
+				var segments []Segment
+				for i, sentence := range splitSentences(text) {
+					segments = append(segments, Segment{Start: time.Duration(i) * time.Second, End: time.Duration(i+1) * time.Second, Text: sentence})
+				}
+				transcript := &Transcript{Text: text, Segments: segments}
+				for i, chunk := range transcript.chunks(limit, count) {
+					if n := count(chunk.Text); n > limit {
+						t.Errorf("第 %d 个片段块有 %d 个 token，超过上限 %d", i+1, n, limit)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"mixed chinese and english", "中文。English. 混合！", []string{"中文。", "English.", "混合！"}},
+		{"question marks", "你好吗？How are you? 很好。", []string{"你好吗？", "How are you?", "很好。"}},
+		{"chinese without spaces", "第一句。第二句！第三句？", []string{"第一句。", "第二句！", "第三句？"}},
+		{"decimal", "圆周率约为 3.14 左右。Pi is 3.14159 roughly.", []string{"圆周率约为 3.14 左右。", "Pi is 3.14159 roughly."}},
+		{"abbreviations", "Use tools, e.g. Go or Rust. Mr. Smith and Dr. Lee met at 9 a.m. in the U.S. office. 然后继续。", []string{"Use tools, e.g. Go or Rust.", "Mr. Smith and Dr. Lee met at 9 a.m. in the U.S. office.", "然后继续。"}},
+		{"version and domain", "升级到 v1.2.3 版本，访问 example.com 查看。", []string{"升级到 v1.2.3 版本，访问 example.com 查看。"}},
+		{"closing quotes", "他说：“好的。”然后离开了。She said \"yes.\" Then left.", []string{"他说：“好的。”", "然后离开了。", "She said \"yes.\"", "Then left."}},
+		{"repeated punctuation", "真的吗？！Really?! 是的……", []string{"真的吗？！", "Really?!", "是的……"}},
+		{"no ending punctuation", "没有句末标点的文本", []string{"没有句末标点的文本"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitSentences(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitSentences(%q) = %q，期望 %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package videonote
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTimestamp 解析 -start/-end 的时间，支持 1:02:03、10:30、90 (秒) 和 25m 等写法
+func ParseTimestamp(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil && strings.ContainsAny(s, "hms") {
+		return d, nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("无效的时间: %q (应为 hh:mm:ss、mm:ss 或秒数)", s)
+	}
+	var d time.Duration
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		// 只有秒可以带小数，分和秒不能超过 59
+		if err != nil || v < 0 || (i < len(parts)-1 && v != float64(int(v))) || (i > 0 && v >= 60) {
+			return 0, fmt.Errorf("无效的时间: %q (应为 hh:mm:ss、mm:ss 或秒数)", s)
+		}
+		d = d*60 + secondsToDuration(v)
+	}
+	return d, nil
+}
+
+// rangeArgs 返回截取时间段的 ffmpeg 输入参数，放在 -i 之前以便快速定位
+func (o ExtractOptions) rangeArgs() []string {
+	var args []string
+	if o.Start > 0 {
+		args = append(args, "-ss", formatSeconds(o.Start))
+	}
+	if o.End > 0 {
+		args = append(args, "-to", formatSeconds(o.End))
+	}
+	return args
+}
+
+// checkRange 确认 -start/-end 没有超出媒体时长
+func checkRange(ctx context.Context, config *Config, mediaPath string, start, end time.Duration) error {
+	duration, err := probeDuration(ctx, config, mediaPath)
+	if err != nil {
+		return fmt.Errorf("获取时长失败: %w", err)
+	}
+	if start >= duration {
+		return fmt.Errorf("-start %s 超出了媒体时长 %s", formatTimestamp(start), formatTimestamp(duration))
+	}
+	if end > duration {
+		return fmt.Errorf("-end %s 超出了媒体时长 %s", formatTimestamp(end), formatTimestamp(duration))
+	}
+	return nil
+}
+
+// clipChapters 只保留与截取时间段重叠的章节，并将首尾章节裁剪到该时间段内
+func clipChapters(chapters []Chapter, start, end time.Duration) []Chapter {
+	if start <= 0 && end <= 0 {
+		return chapters
+	}
+	var clipped []Chapter
+	for _, c := range chapters {
+		if c.End <= start || (end > 0 && c.Start >= end) {
+			continue
+		}
+		c.Start = max(c.Start, start)
+		if end > 0 {
+			c.End = min(c.End, end)
+		}
+		clipped = append(clipped, c)
+	}
+	return clipped
+}
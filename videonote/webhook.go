@@ -0,0 +1,115 @@
+package videonote
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WebhookSignatureHeader 为请求体 HMAC-SHA256 签名所在的请求头，值为 "sha256=" 加十六进制签名
+const WebhookSignatureHeader = "X-Video-Note-Signature"
+
+// 运行结束时通知 webhook 的状态
+const (
+	WebhookSucceeded = "succeeded"
+	WebhookPartial   = "partial"
+	WebhookFailed    = "failed"
+	WebhookCanceled  = "canceled"
+)
+
+// webhookTimeout 为单次投递的超时时间
+const webhookTimeout = 30 * time.Second
+
+// WebhookPayload 为运行结束时 POST 到 -webhook 的 JSON，字段名保持稳定供下游解析
+type WebhookPayload struct {
+	Status  string `json:"status"`
+	Command string `json:"command"`
+	// Notes 为本次运行写入的笔记路径，写入标准输出的不包括在内
+	Notes      []string  `json:"notes"`
+	Error      string    `json:"error,omitempty"`
+	ExitCode   int       `json:"exit_code"`
+	Stats      Report    `json:"stats"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// CheckWebhookURL 检查 -webhook 是否为 http 或 https 地址
+func CheckWebhookURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("无效的 -webhook: %q，需要 http 或 https 地址", s)
+	}
+	return nil
+}
+
+// NotifyWebhook 将 payload POST 到 webhookURL；配置了 webhook_secret 时附带签名。
+// 网络错误、限流和服务端错误按指数退避重试，最多尝试 max_attempts 次
+func NotifyWebhook(ctx context.Context, config *Config, webhookURL string, payload WebhookPayload) error {
+	// 保证 notes 总是数组，方便下游直接遍历
+	if payload.Notes == nil {
+		payload.Notes = []string{}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("编码webhook内容失败: %w", err)
+	}
+
+	attempts := 0
+	err = withRetry(ctx, config.MaxAttempts, func() error {
+		attempts++
+		return postWebhook(ctx, webhookURL, config.WebhookSecret, body)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("通知webhook失败: %w", err)
+		}
+		return fmt.Errorf("通知webhook失败 (已尝试%d次): %w", attempts, err)
+	}
+	debugf("webhook已送达: %s", webhookURL)
+	return nil
+}
+
+// postWebhook 发送一次请求；连接失败、超时和非 2xx 响应返回 *httpStatusError，由 withRetry 判断是否重试
+func postWebhook(ctx context.Context, webhookURL, secret string, body []byte) error {
+	reqCtx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+signWebhook(secret, body))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// 整个运行被取消时不再重试；连接失败或单次请求超时时接收方可能暂时不可用
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &httpStatusError{Err: err}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return &httpStatusError{StatusCode: resp.StatusCode, Err: fmt.Errorf("接收方返回 %s", resp.Status)}
+}
+
+// signWebhook 返回 body 的 HMAC-SHA256 签名 (十六进制)
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,81 @@
+package videonote
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNotifyWebhookRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantErr   bool
+		wantCalls int32
+	}{
+		{"success", []int{http.StatusNoContent}, false, 1},
+		{"retry server error", []int{http.StatusServiceUnavailable, http.StatusOK}, false, 2},
+		{"client error not retried", []int{http.StatusBadRequest, http.StatusOK}, true, 1},
+		{"gives up after max attempts", []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				w.WriteHeader(tt.statuses[min(int(n), len(tt.statuses))-1])
+			}))
+			defer srv.Close()
+
+			err := NotifyWebhook(context.Background(), &Config{MaxAttempts: 2}, srv.URL, WebhookPayload{Status: WebhookSucceeded})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v，期望出错: %v", err, tt.wantErr)
+			}
+			if n := calls.Load(); n != tt.wantCalls {
+				t.Errorf("收到 %d 个请求，期望 %d 个", n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// webhook 的投递和重试不是 OpenAI 接口调用，不计入 -report 的接口调用次数
+func TestNotifyWebhookNotCounted(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	before := apiCallCount()
+	if err := NotifyWebhook(context.Background(), &Config{MaxAttempts: 2}, srv.URL, WebhookPayload{Status: WebhookSucceeded}); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("收到 %d 个请求，期望重试一次共 2 个", calls.Load())
+	}
+	if n := apiCallCount() - before; n != 0 {
+		t.Errorf("webhook 投递计入了 %d 次接口调用", n)
+	}
+
+	// 对照：转录和摘要的请求计入接口调用
+	api := newFakeAPI(t)
+	before = apiCallCount()
+	if _, err := Generate(context.Background(), api.config(t), writeTestFile(t, "talk.srt", testSRT), filepath.Join(t.TempDir(), "talk.txt"), testGenerateOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := apiCallCount()-before, api.Calls(completionsPath); got != want {
+		t.Errorf("计入 %d 次接口调用，期望 %d 次", got, want)
+	}
+}
+
+// apiCallCount 返回全局统计中的接口调用次数
+func apiCallCount() int {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	return metrics.apiCalls
+}
@@ -0,0 +1,66 @@
+package videonote
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultMinLength 为命令行 -min-length 的默认值：转录少于该字数时不生成摘要，
+// 约为半分钟到一分钟的讲话
+const DefaultMinLength = 150
+
+// DefaultRatio 为命令行 -ratio 的默认值
+const DefaultRatio = 0.2
+
+// CheckRatio 检查 -ratio，generate、summarize 和 serve 共用
+func CheckRatio(ratio float64) error {
+	if ratio < 0.1 || ratio > 0.5 {
+		return fmt.Errorf("摘要比例必须在0.1-0.5之间")
+	}
+	return nil
+}
+
+// CheckWords 检查 -words；问答卡片的数量由知识点决定，不按字数控制
+func CheckWords(words int, f Format) error {
+	if words < 0 {
+		return fmt.Errorf("-words 不能为负数")
+	}
+	if words > 0 && f.isFlashcards() {
+		return fmt.Errorf("-words 不适用于 %s 格式", f)
+	}
+	return nil
+}
+
+// WordsRatio 返回目标字数相对于原文的摘要比例，超过原文长度时为 1
+func WordsRatio(text string, words int) float64 {
+	length := textLength(text)
+	if length == 0 {
+		return 1
+	}
+	return math.Min(float64(words)/float64(length), 1)
+}
+
+// chunkWords 按各块原文的长度分配目标字数，累计取整使各块之和等于 words
+func chunkWords(chunks []textChunk, words int) []int {
+	lengths := make([]int, len(chunks))
+	total := 0
+	for i, chunk := range chunks {
+		lengths[i] = textLength(chunk.Text)
+		total += lengths[i]
+	}
+
+	targets := make([]int, len(chunks))
+	sum, assigned := 0, 0
+	for i, length := range lengths {
+		sum += length
+		var end int
+		if total > 0 {
+			end = int(math.Round(float64(words) * float64(sum) / float64(total)))
+		} else {
+			end = words * (i + 1) / len(chunks)
+		}
+		targets[i] = max(end-assigned, 1)
+		assigned = end
+	}
+	return targets
+}